@@ -36,7 +36,7 @@ type Account struct {
 // https://developers.viber.com/docs/api/rest-bot-api/#get-account-info
 func (v *Viber) AccountInfo() (Account, error) {
 	var a Account
-	b, err := v.PostData("https://chatapi.viber.com/pa/get_account_info", struct{}{})
+	b, err := v.PostData(v.apiURL("get_account_info"), struct{}{})
 	if err != nil {
 		return a, err
 	}
@@ -0,0 +1,158 @@
+package viber
+
+import "errors"
+
+// API versions required by message features
+const (
+	richMediaAPIVersion      = 2
+	pickerActionsAPIVersion  = 3
+	defaultMessageAPIVersion = 1
+)
+
+// ErrAPIVersion is returned when message can't be downgraded for user's client API version
+var ErrAPIVersion = errors.New("viber: message not supported by user's API version and has no fallback")
+
+// textMessager is implemented by TextMessage and all messages embedding it
+type textMessager interface {
+	textMessage() *TextMessage
+}
+
+func (m *TextMessage) textMessage() *TextMessage {
+	return m
+}
+
+// RequiredAPIVersion returns minimal Viber client API version needed to display message m,
+// based on message type, keyboard and button action types it uses
+func RequiredAPIVersion(m Message) int {
+	switch m := m.(type) {
+	case *RichMediaMessage:
+		return max(richMediaAPIVersion, buttonsAPIVersion(m.RichMedia.Buttons), keyboardAPIVersion(m.Keyboard))
+	case textMessager:
+		return keyboardAPIVersion(m.textMessage().Keyboars)
+	}
+	return defaultMessageAPIVersion
+}
+
+func keyboardAPIVersion(k *Keyboard) int {
+	if k == nil {
+		return defaultMessageAPIVersion
+	}
+	return buttonsAPIVersion(k.Buttons)
+}
+
+func buttonsAPIVersion(buttons []Button) int {
+	ver := defaultMessageAPIVersion
+	for _, b := range buttons {
+		ver = max(ver, buttonAPIVersion(b))
+	}
+	return ver
+}
+
+func buttonAPIVersion(b Button) int {
+	switch b.ActionType {
+	case LocationPicker, SharePhone:
+		return pickerActionsAPIVersion
+	}
+	return defaultMessageAPIVersion
+}
+
+// setMinAPIVersion raises min_api_version of m to the version its features require
+func setMinAPIVersion(m Message) {
+	ver := RequiredAPIVersion(m)
+	switch m := m.(type) {
+	case *RichMediaMessage:
+		if m.MinAPIVersion < ver {
+			m.MinAPIVersion = ver
+		}
+	case textMessager:
+		if tm := m.textMessage(); ver > defaultMessageAPIVersion && tm.MinAPIVersion < uint(ver) {
+			tm.MinAPIVersion = uint(ver)
+		}
+	}
+}
+
+// Fallback returns message which can be displayed on client with apiVersion.
+// Rich media message is replaced with text message containing its AltText, buttons which are not supported
+// are removed from keyboards. Message m is returned unchanged if client supports it, m itself is never modified.
+func (v *Viber) Fallback(m Message, apiVersion int) (Message, error) {
+	if apiVersion <= 0 || RequiredAPIVersion(m) <= apiVersion {
+		return m, nil
+	}
+
+	switch m := m.(type) {
+	case *RichMediaMessage:
+		if apiVersion < richMediaAPIVersion {
+			if m.AltText == "" {
+				return nil, ErrAPIVersion
+			}
+			return &TextMessage{
+				Receiver:     m.Receiver,
+				Sender:       v.Sender,
+				Type:         TypeTextMessage,
+				TrackingData: m.TrackingData,
+				Text:         m.AltText,
+				Keyboars:     downgradeKeyboard(m.Keyboard, apiVersion),
+			}, nil
+		}
+		c := *m
+		c.Keyboard = downgradeKeyboard(m.Keyboard, apiVersion)
+		c.MinAPIVersion = 0
+		return &c, nil
+
+	case *TextMessage:
+		c := *m
+		c.Keyboars = downgradeKeyboard(m.Keyboars, apiVersion)
+		c.MinAPIVersion = 0
+		return &c, nil
+	case *URLMessage:
+		c := *m
+		c.Keyboars = downgradeKeyboard(m.Keyboars, apiVersion)
+		c.MinAPIVersion = 0
+		return &c, nil
+	case *PictureMessage:
+		c := *m
+		c.Keyboars = downgradeKeyboard(m.Keyboars, apiVersion)
+		c.MinAPIVersion = 0
+		return &c, nil
+	case *VideoMessage:
+		c := *m
+		c.Keyboars = downgradeKeyboard(m.Keyboars, apiVersion)
+		c.MinAPIVersion = 0
+		return &c, nil
+	case *FileMessage:
+		c := *m
+		c.Keyboars = downgradeKeyboard(m.Keyboars, apiVersion)
+		c.MinAPIVersion = 0
+		return &c, nil
+	}
+	return nil, ErrAPIVersion
+}
+
+// downgradeKeyboard returns copy of k without buttons unsupported by apiVersion, nil if no button is left
+func downgradeKeyboard(k *Keyboard, apiVersion int) *Keyboard {
+	if k == nil {
+		return nil
+	}
+
+	c := *k
+	c.Buttons = nil
+	for _, b := range k.Buttons {
+		if buttonAPIVersion(b) <= apiVersion {
+			c.Buttons = append(c.Buttons, b)
+		}
+	}
+	if len(c.Buttons) == 0 {
+		return nil
+	}
+	return &c
+}
+
+// SendMessageToUser sends message m to user u, downgraded by Fallback if user's client
+// doesn't support features message uses
+func (v *Viber) SendMessageToUser(u User, m Message) (msgToken uint64, err error) {
+	fm, err := v.Fallback(m, u.APIVersion)
+	if err != nil {
+		return 0, err
+	}
+	return v.SendMessage(u.ID, fm)
+}
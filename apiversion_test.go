@@ -0,0 +1,82 @@
+package viber
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestRequiredAPIVersion(t *testing.T) {
+	v := New("key", "bot", "")
+	m := v.NewTextMessage("hi")
+	if got := RequiredAPIVersion(m); got != 1 {
+		t.Fatalf("text message requires %d, want 1", got)
+	}
+
+	k := v.NewKeyboard("", false)
+	k.AddButton(v.NewTextButton(6, 1, SharePhone, "phone", "Share phone"))
+	m.SetKeyboard(k)
+	if got := RequiredAPIVersion(m); got != 3 {
+		t.Fatalf("share-phone keyboard requires %d, want 3", got)
+	}
+	if got := RequiredAPIVersion(v.NewRichMediaMessage(6, 7, "#FFFFFF")); got != 2 {
+		t.Fatalf("rich media requires %d, want 2", got)
+	}
+}
+
+func TestFallback(t *testing.T) {
+	v := New("key", "bot", "")
+	m := v.NewTextMessage("hi")
+	k := v.NewKeyboard("", false)
+	k.AddButton(v.NewTextButton(6, 1, SharePhone, "phone", "Share phone"))
+	k.AddButton(v.NewTextButton(6, 1, Reply, "ok", "OK"))
+	m.SetKeyboard(k)
+
+	fm, err := v.Fallback(m, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := fm.(*TextMessage).Keyboars.Buttons; len(b) != 1 || b[0].ActionType != Reply {
+		t.Fatalf("fallback keyboard %+v", b)
+	}
+	if len(m.Keyboars.Buttons) != 2 {
+		t.Fatal("Fallback modified original message")
+	}
+
+	r := v.NewRichMediaMessage(6, 7, "#FFFFFF")
+	if _, err := v.Fallback(r, 1); !errors.Is(err, ErrAPIVersion) {
+		t.Fatalf("rich media without AltText: %v", err)
+	}
+	r.AltText = "See our offers"
+	fm, err = v.Fallback(r, 1)
+	if err != nil || fm.(*TextMessage).Text != "See our offers" {
+		t.Fatalf("rich media fallback %+v, %v", fm, err)
+	}
+}
+
+func TestWelcomeMessageFallbackError(t *testing.T) {
+	v := New("key", "bot", "")
+	var reported error
+	v.OnError = func(v *Viber, err *WebhookError) { reported = err.Err }
+	v.ConversationStarted = func(v *Viber, u User, typ, ctx string, subscribed bool, token uint64, t time.Time) Message {
+		return v.NewRichMediaMessage(6, 7, "#FFFFFF")
+	}
+
+	w := postWebhook(v, "key", `{"event":"conversation_started","timestamp":1,"message_token":1,"user":{"id":"u1","api_version":1}}`)
+	if w.Code != http.StatusOK || w.Body.Len() != 0 {
+		t.Fatalf("status %d, body %q", w.Code, w.Body.String())
+	}
+	if !errors.Is(reported, ErrAPIVersion) {
+		t.Fatalf("reported error %v", reported)
+	}
+
+	w = postWebhook(v, "key", `{"event":"conversation_started","timestamp":1,"message_token":2,"user":{"id":"u1","api_version":3}}`)
+	var resp struct {
+		MinAPIVersion int `json:"min_api_version"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || resp.MinAPIVersion != 2 {
+		t.Fatalf("welcome message %s", w.Body.String())
+	}
+}
@@ -0,0 +1,70 @@
+package viber
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// MaxBroadcastReceivers per one broadcast_message call
+const MaxBroadcastReceivers = 300
+
+// ErrTooManyReceivers is returned by Broadcast for more than MaxBroadcastReceivers receivers
+var ErrTooManyReceivers = errors.New("viber: too many broadcast receivers")
+
+// BroadcastFailure of one receiver in broadcast
+type BroadcastFailure struct {
+	Receiver      string `json:"receiver"`
+	Status        int    `json:"status"`
+	StatusMessage string `json:"status_message"`
+}
+
+type broadcastResponse struct {
+	Status        int                `json:"status"`
+	StatusMessage string             `json:"status_message"`
+	MessageToken  uint64             `json:"message_token"`
+	FailedList    []BroadcastFailure `json:"failed_list"`
+}
+
+// Broadcast message m to at most MaxBroadcastReceivers receivers.
+// Returns message token shared by all receivers and the list of receivers message wasn't sent to.
+// https://developers.viber.com/docs/api/rest-bot-api/#broadcast-message
+func (v *Viber) Broadcast(receivers []string, m Message) (msgToken uint64, failed []BroadcastFailure, err error) {
+	if len(receivers) > MaxBroadcastReceivers {
+		return 0, nil, ErrTooManyReceivers
+	}
+
+	if v.CheckMedia {
+		if err := v.Preflight(m); err != nil {
+			return 0, nil, err
+		}
+	}
+
+	m.SetReceiver("")
+	setMinAPIVersion(m)
+	b, err := json.Marshal(m)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	// broadcast request is the message with broadcast_list instead of receiver
+	req := map[string]json.RawMessage{}
+	if err := json.Unmarshal(b, &req); err != nil {
+		return 0, nil, err
+	}
+	list, _ := json.Marshal(receivers)
+	req["broadcast_list"] = list
+
+	r, err := v.PostData(v.apiURL("broadcast_message"), req)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	var resp broadcastResponse
+	if err := json.Unmarshal(r, &resp); err != nil {
+		return 0, nil, err
+	}
+	if resp.Status != 0 {
+		return resp.MessageToken, resp.FailedList, Error{Status: resp.Status, StatusMessage: resp.StatusMessage}
+	}
+	return resp.MessageToken, resp.FailedList, nil
+}
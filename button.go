@@ -96,13 +96,16 @@ func (b *Button) TextSizeLarge() *Button {
 // ActionType for carousel buttons
 // viber.Reply
 // viber.OpenURL
+// viber.LocationPicker and viber.SharePhone for keyboards, require API version 3
 type ActionType string
 
 // ActionType values
 const (
-	Reply   = ActionType("reply")
-	OpenURL = ActionType("open-url")
-	None    = ActionType("none")
+	Reply          = ActionType("reply")
+	OpenURL        = ActionType("open-url")
+	None           = ActionType("none")
+	LocationPicker = ActionType("location-picker")
+	SharePhone     = ActionType("share-phone")
 )
 
 // TextVAlign for carousel buttons
@@ -0,0 +1,253 @@
+package viber
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Subscriber of public account with custom tags used for audience segmentation
+type Subscriber struct {
+	User
+	Tags []string `json:"tags,omitempty"`
+}
+
+// SubscriberStore provides subscribers for campaigns
+type SubscriberStore interface {
+	Subscribers() ([]Subscriber, error)
+}
+
+// MemorySubscriberStore is in-memory SubscriberStore.
+// Its Subscribed and Unsubscribed methods match Viber callbacks, so the store can be kept
+// up to date directly from webhook events.
+type MemorySubscriberStore struct {
+	mu   sync.RWMutex
+	subs map[string]Subscriber
+}
+
+// NewMemorySubscriberStore returns empty store
+func NewMemorySubscriberStore() *MemorySubscriberStore {
+	return &MemorySubscriberStore{subs: make(map[string]Subscriber)}
+}
+
+// Add or replace subscriber
+func (s *MemorySubscriberStore) Add(sub Subscriber) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.subs[sub.ID] = sub
+}
+
+// Remove subscriber
+func (s *MemorySubscriberStore) Remove(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.subs, id)
+}
+
+// Tag subscriber with tags
+func (s *MemorySubscriberStore) Tag(id string, tags ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if sub, ok := s.subs[id]; ok {
+		sub.Tags = append(sub.Tags, tags...)
+		s.subs[id] = sub
+	}
+}
+
+// Subscribers returns all subscribers ordered by id
+func (s *MemorySubscriberStore) Subscribers() ([]Subscriber, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	subs := make([]Subscriber, 0, len(s.subs))
+	for _, sub := range s.subs {
+		subs = append(subs, sub)
+	}
+	sort.Slice(subs, func(i, j int) bool { return subs[i].ID < subs[j].ID })
+	return subs, nil
+}
+
+// Subscribed callback adds user to the store, keeping existing tags
+func (s *MemorySubscriberStore) Subscribed(v *Viber, u User, token uint64, t time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sub := s.subs[u.ID]
+	sub.User = u
+	s.subs[u.ID] = sub
+}
+
+// Unsubscribed callback removes user from the store
+func (s *MemorySubscriberStore) Unsubscribed(v *Viber, userID string, token uint64, t time.Time) {
+	s.Remove(userID)
+}
+
+// CampaignStats aggregated from broadcast responses and delivery callbacks.
+// Each recipient is counted either as Sent or as Failed.
+type CampaignStats struct {
+	Recipients int
+	Sent       int
+	Delivered  int
+	Seen       int
+	Failed     int
+}
+
+// Campaign is message broadcast to subscribers selected by filter
+type Campaign struct {
+	ID       string
+	Filter   string
+	Started  time.Time
+	Finished time.Time
+	Tokens   []uint64
+	Stats    CampaignStats
+}
+
+// CampaignManager sends campaigns through broadcast API and aggregates their delivery stats.
+// Assign its Delivered, Seen and Failed methods to Viber callbacks, or call them from your own callbacks.
+type CampaignManager struct {
+	// Limiter between broadcast calls, nil sends without limit
+	Limiter Limiter
+
+	v     *Viber
+	store SubscriberStore
+
+	mu        sync.Mutex
+	campaigns map[string]*Campaign
+	tokens    map[uint64]string // message token to campaign id
+}
+
+// ErrNoRecipients is returned when campaign filter matches no subscribers
+var ErrNoRecipients = errors.New("viber: campaign has no recipients")
+
+// NewCampaignManager returns campaign manager of v selecting recipients from store
+func (v *Viber) NewCampaignManager(store SubscriberStore) *CampaignManager {
+	return &CampaignManager{
+		v:         v,
+		store:     store,
+		campaigns: make(map[string]*Campaign),
+		tokens:    make(map[uint64]string),
+	}
+}
+
+// Send message m to subscribers matching filter expression (see Filter).
+// Recipients are split into broadcasts of MaxBroadcastReceivers.
+// Campaign is returned even if some of the broadcasts failed, with the first error.
+func (cm *CampaignManager) Send(ctx context.Context, filter string, m Message) (Campaign, error) {
+	f, err := ParseFilter(filter)
+	if err != nil {
+		return Campaign{}, err
+	}
+
+	subs, err := cm.store.Subscribers()
+	if err != nil {
+		return Campaign{}, err
+	}
+	var ids []string
+	for _, s := range subs {
+		if f.Match(s) {
+			ids = append(ids, s.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return Campaign{}, ErrNoRecipients
+	}
+
+	c := &Campaign{
+		ID:      newID(),
+		Filter:  filter,
+		Started: time.Now(),
+		Stats:   CampaignStats{Recipients: len(ids)},
+	}
+	cm.mu.Lock()
+	cm.campaigns[c.ID] = c
+	cm.mu.Unlock()
+
+	v := cm.v.WithContext(ctx)
+	var firstErr error
+	for i := 0; i < len(ids); i += MaxBroadcastReceivers {
+		batch := ids[i:min(i+MaxBroadcastReceivers, len(ids))]
+
+		if cm.Limiter != nil {
+			if err := cm.Limiter.Wait(ctx); err != nil {
+				firstErr = err
+				break
+			}
+		}
+
+		token, failed, err := v.Broadcast(batch, m)
+		cm.mu.Lock()
+		if token != 0 {
+			c.Tokens = append(c.Tokens, token)
+			cm.tokens[token] = c.ID
+		}
+		if err != nil {
+			c.Stats.Failed += len(batch)
+		} else {
+			c.Stats.Sent += len(batch) - len(failed)
+			c.Stats.Failed += len(failed)
+		}
+		cm.mu.Unlock()
+
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	cm.mu.Lock()
+	c.Finished = time.Now()
+	result := *c
+	cm.mu.Unlock()
+	return result, firstErr
+}
+
+// Campaign returns current state of campaign with id
+func (cm *CampaignManager) Campaign(id string) (Campaign, bool) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	c, ok := cm.campaigns[id]
+	if !ok {
+		return Campaign{}, false
+	}
+	return *c, true
+}
+
+// Campaigns returns all campaigns ordered by start time
+func (cm *CampaignManager) Campaigns() []Campaign {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	list := make([]Campaign, 0, len(cm.campaigns))
+	for _, c := range cm.campaigns {
+		list = append(list, *c)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Started.Before(list[j].Started) })
+	return list
+}
+
+// Delivered callback counts delivery of campaign message
+func (cm *CampaignManager) Delivered(v *Viber, userID string, token uint64, t time.Time) {
+	cm.count(token, func(s *CampaignStats) { s.Delivered++ })
+}
+
+// Seen callback counts seen campaign message
+func (cm *CampaignManager) Seen(v *Viber, userID string, token uint64, t time.Time) {
+	cm.count(token, func(s *CampaignStats) { s.Seen++ })
+}
+
+// Failed callback counts failed campaign message. Message was counted as sent by broadcast response,
+// so it is moved from Sent to Failed.
+func (cm *CampaignManager) Failed(v *Viber, userID string, token uint64, descr string, t time.Time) {
+	cm.count(token, func(s *CampaignStats) {
+		if s.Sent > 0 {
+			s.Sent--
+			s.Failed++
+		}
+	})
+}
+
+func (cm *CampaignManager) count(token uint64, f func(s *CampaignStats)) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	if id, ok := cm.tokens[token]; ok {
+		f(&cm.campaigns[id].Stats)
+	}
+}
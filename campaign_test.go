@@ -0,0 +1,48 @@
+package viber
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCampaignStats(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"status":0,"status_message":"ok","message_token":99,
+			"failed_list":[{"receiver":"u3","status":6,"status_message":"Not subscribed"}]}`)
+	}))
+	defer srv.Close()
+
+	v := New("key", "bot", "", WithBaseURL(srv.URL))
+	store := NewMemorySubscriberStore()
+	for _, id := range []string{"u1", "u2", "u3", "u4"} {
+		store.Add(Subscriber{User: User{ID: id, Country: "RS"}})
+	}
+	store.Add(Subscriber{User: User{ID: "u5", Country: "DE"}})
+
+	cm := v.NewCampaignManager(store)
+	c, err := cm.Send(context.Background(), `country == "RS"`, v.NewTextMessage("hi"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Stats.Recipients != 4 || c.Stats.Sent != 3 || c.Stats.Failed != 1 {
+		t.Fatalf("stats after broadcast %+v", c.Stats)
+	}
+
+	cm.Delivered(v, "u1", 99, time.Now())
+	cm.Seen(v, "u1", 99, time.Now())
+	cm.Failed(v, "u2", 99, "failed", time.Now())
+	cm.Delivered(v, "u1", 12345, time.Now()) // other message
+
+	c, _ = cm.Campaign(c.ID)
+	want := CampaignStats{Recipients: 4, Sent: 2, Delivered: 1, Seen: 1, Failed: 2}
+	if c.Stats != want {
+		t.Fatalf("stats %+v, want %+v", c.Stats, want)
+	}
+	if c.Stats.Sent+c.Stats.Failed != c.Stats.Recipients {
+		t.Fatal("sent and failed don't add up to recipients")
+	}
+}
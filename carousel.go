@@ -29,7 +29,7 @@ func (rm *RichMediaMessage) AddButton(b *Button) {
 // NewRichMediaMessage creates new empty carousel message
 func (v *Viber) NewRichMediaMessage(cols, rows int, bgColor string) *RichMediaMessage {
 	return &RichMediaMessage{
-		MinAPIVersion: 2,
+		MinAPIVersion: richMediaAPIVersion,
 		AuthToken:     v.AppKey,
 		Type:          TypeRichMediaMessage,
 		RichMedia: RichMedia{
@@ -0,0 +1,104 @@
+package viber
+
+import (
+	"container/list"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DedupStore remembers webhook deliveries which are already processed.
+// Viber retries callbacks when webhook is slow to respond, so the same event can arrive more than once.
+type DedupStore interface {
+	// Mark key as processed, reports whether key wasn't marked already.
+	// Check and mark must be atomic, so concurrent retry of the same event isn't processed twice.
+	Mark(key string) bool
+	// Forget marked key, so the event is processed again
+	Forget(key string)
+}
+
+// TokenCache is in-memory LRU DedupStore with expiring entries
+type TokenCache struct {
+	size int
+	ttl  time.Duration
+
+	mu sync.Mutex
+	ll *list.List
+	m  map[string]*list.Element
+}
+
+type tokenEntry struct {
+	key     string
+	expires time.Time
+}
+
+// NewTokenCache returns TokenCache which keeps at most size keys, each for ttl duration
+func NewTokenCache(size int, ttl time.Duration) *TokenCache {
+	return &TokenCache{
+		size: size,
+		ttl:  ttl,
+		ll:   list.New(),
+		m:    make(map[string]*list.Element),
+	}
+}
+
+// Seen reports whether key was marked and hasn't expired
+func (c *TokenCache) Seen(key string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.m[key]
+	if !ok {
+		return false
+	}
+	c.ll.MoveToFront(el)
+	return time.Now().Before(el.Value.(*tokenEntry).expires)
+}
+
+// Mark key as processed for cache ttl, reports whether key wasn't marked or has expired
+func (c *TokenCache) Mark(key string) bool {
+	now := time.Now()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.m[key]; ok {
+		c.ll.MoveToFront(el)
+		e := el.Value.(*tokenEntry)
+		if now.Before(e.expires) {
+			return false
+		}
+		e.expires = now.Add(c.ttl)
+		return true
+	}
+
+	c.m[key] = c.ll.PushFront(&tokenEntry{key: key, expires: now.Add(c.ttl)})
+
+	// evict least recently used and expired entries
+	for el := c.ll.Back(); el != nil; el = c.ll.Back() {
+		e := el.Value.(*tokenEntry)
+		if c.ll.Len() <= c.size && now.Before(e.expires) {
+			break
+		}
+		c.ll.Remove(el)
+		delete(c.m, e.key)
+	}
+	return true
+}
+
+// Forget marked key
+func (c *TokenCache) Forget(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.m[key]; ok {
+		c.ll.Remove(el)
+		delete(c.m, key)
+	}
+}
+
+// dedupKey of event. Delivered, seen and failed events share the message token of sent message,
+// so event name and user id are part of the key.
+func dedupKey(e *event) string {
+	return fmt.Sprintf("%s:%d:%s", e.Event, e.MessageToken, e.UserID)
+}
@@ -0,0 +1,54 @@
+package viber
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTokenCache(t *testing.T) {
+	c := NewTokenCache(2, time.Hour)
+	if c.Seen("a") {
+		t.Fatal("unmarked key seen")
+	}
+	if c.Seen("a") {
+		t.Fatal("Seen marked the key")
+	}
+
+	if !c.Mark("a") || !c.Mark("b") {
+		t.Fatal("new keys not reported as new")
+	}
+	if c.Mark("a") {
+		t.Fatal("marked key reported as new")
+	}
+	if !c.Seen("a") || !c.Seen("b") {
+		t.Fatal("marked keys not seen")
+	}
+
+	// a is used more recently than b, so b is evicted
+	c.Seen("a")
+	c.Mark("c")
+	if !c.Seen("a") || c.Seen("b") || !c.Seen("c") {
+		t.Fatal("least recently used key not evicted")
+	}
+}
+
+func TestTokenCacheExpiry(t *testing.T) {
+	c := NewTokenCache(10, 10*time.Millisecond)
+	c.Mark("a")
+	time.Sleep(20 * time.Millisecond)
+	if c.Seen("a") {
+		t.Fatal("expired key seen")
+	}
+	if !c.Mark("a") {
+		t.Fatal("expired key not reported as new")
+	}
+}
+
+func TestTokenCacheForget(t *testing.T) {
+	c := NewTokenCache(10, time.Hour)
+	c.Mark("a")
+	c.Forget("a")
+	if c.Seen("a") || !c.Mark("a") {
+		t.Fatal("forgotten key still marked")
+	}
+}
@@ -0,0 +1,85 @@
+package viber
+
+import (
+	"context"
+	"sync"
+)
+
+// Dispatcher runs event callbacks.
+// If Viber has no Dispatcher set, each callback runs in its own goroutine.
+type Dispatcher interface {
+	Dispatch(f func())
+}
+
+// WorkerPool is Dispatcher which runs callbacks on fixed number of goroutines
+type WorkerPool struct {
+	queue chan func()
+	wg    sync.WaitGroup
+}
+
+// NewWorkerPool starts workers goroutines with queue for queueSize pending callbacks
+func NewWorkerPool(workers, queueSize int) *WorkerPool {
+	if workers < 1 {
+		workers = 1
+	}
+
+	p := &WorkerPool{
+		queue: make(chan func(), queueSize),
+	}
+	p.wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer p.wg.Done()
+			for f := range p.queue {
+				f()
+			}
+		}()
+	}
+	return p
+}
+
+// Dispatch queues f for execution, blocks while the queue is full
+func (p *WorkerPool) Dispatch(f func()) {
+	p.queue <- f
+}
+
+// Len returns number of callbacks waiting in the queue
+func (p *WorkerPool) Len() int {
+	return len(p.queue)
+}
+
+// Close waits for queued callbacks to finish and stops the workers.
+// Dispatch must not be called after Close.
+func (p *WorkerPool) Close() {
+	close(p.queue)
+	p.wg.Wait()
+}
+
+// dispatch event callback f using v.Dispatcher or in new goroutine.
+// f receives v bound to callback span context.
+func (v *Viber) dispatch(ctx context.Context, event EventType, f func(v *Viber)) {
+	run := func() {
+		ctx, span := v.startSpan(ctx, "viber.callback")
+		defer span.End()
+		span.SetAttribute("event", string(event))
+		v.callback(event, func() { f(v.callbackBot(ctx)) })
+	}
+
+	if v.Dispatcher == nil {
+		go run()
+		return
+	}
+
+	v.Dispatcher.Dispatch(func() {
+		v.queueDepth()
+		run()
+	})
+	v.queueDepth()
+}
+
+// queueDepth reports Dispatcher queue length to Metrics
+func (v *Viber) queueDepth() {
+	if q, ok := v.Dispatcher.(interface{ Len() int }); ok && v.Metrics != nil {
+		v.Metrics.QueueDepth(q.Len())
+	}
+}
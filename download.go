@@ -0,0 +1,191 @@
+package viber
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+// Errors returned by Downloader, wrapped with media URL
+var (
+	ErrMediaTooLarge = errors.New("media exceeds size limit")
+	ErrMediaType     = errors.New("media content type not allowed")
+)
+
+// Attachment is inbound media saved by Downloader
+type Attachment struct {
+	// URL of media in the sink and temporary Viber URL it was downloaded from
+	URL    string
+	Source string
+
+	Name        string
+	ContentType string
+	Size        int64
+	SHA256      string
+
+	// Width and Height of images, zero for other media
+	Width  int
+	Height int
+}
+
+// Downloader saves media of messages received from users, since Viber media URLs expire
+type Downloader struct {
+	// MaxSize of media in bytes, MaxFileSize if not set
+	MaxSize int64
+
+	// Timeout of download, 30s if not set. Media isn't fetched with API client, so WithRequestTimeout doesn't apply.
+	Timeout time.Duration
+
+	// ContentTypes allowed, "image/" allows any image type. Empty allows all media.
+	ContentTypes []string
+
+	v    *Viber
+	sink MediaStore
+}
+
+// defaultDownloadTimeout of Downloader
+const defaultDownloadTimeout = 30 * time.Second
+
+// NewDownloader returns downloader of v which saves media to sink, nil sink is v.MediaStore
+func (v *Viber) NewDownloader(sink MediaStore) *Downloader {
+	return &Downloader{v: v, sink: sink}
+}
+
+// Download media of picture, video or file message m
+func (d *Downloader) Download(ctx context.Context, m Message) (Attachment, error) {
+	switch m := m.(type) {
+	case *PictureMessage:
+		return d.DownloadURL(ctx, m.Media, "")
+	case *VideoMessage:
+		return d.DownloadURL(ctx, m.Media, "")
+	case *FileMessage:
+		return d.DownloadURL(ctx, m.Media, m.FileName)
+	}
+	return Attachment{}, errors.New("viber: message has no media")
+}
+
+// DownloadURL downloads media from mediaURL and saves it to the sink under name,
+// empty name is taken from the URL
+func (d *Downloader) DownloadURL(ctx context.Context, mediaURL string, name string) (Attachment, error) {
+	sink := d.sink
+	if sink == nil {
+		sink = d.v.MediaStore
+	}
+	if sink == nil {
+		return Attachment{}, ErrNoMediaStore
+	}
+
+	a, err := d.download(ctx, sink, mediaURL, name)
+	if err != nil {
+		return Attachment{}, fmt.Errorf("viber: download %s: %w", mediaURL, err)
+	}
+	return a, nil
+}
+
+func (d *Downloader) download(ctx context.Context, sink MediaStore, mediaURL string, name string) (Attachment, error) {
+	maxSize := d.MaxSize
+	if maxSize <= 0 {
+		maxSize = MaxFileSize
+	}
+	timeout := d.Timeout
+	if timeout <= 0 {
+		timeout = defaultDownloadTimeout
+	}
+	if name == "" {
+		name = mediaName(mediaURL)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "GET", mediaURL, nil)
+	if err != nil {
+		return Attachment{}, err
+	}
+	resp, err := d.v.mediaHTTPClient().Do(req)
+	if err != nil {
+		return Attachment{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return Attachment{}, fmt.Errorf("unexpected %s", resp.Status)
+	}
+	if resp.ContentLength > maxSize {
+		return Attachment{}, ErrMediaTooLarge
+	}
+
+	// media is buffered in temp file, so it is hashed and checked before it reaches the sink
+	f, err := os.CreateTemp("", "viber-media-")
+	if err != nil {
+		return Attachment{}, err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	h := sha256.New()
+	n, err := io.Copy(io.MultiWriter(f, h), io.LimitReader(resp.Body, maxSize+1))
+	if err != nil {
+		return Attachment{}, err
+	}
+	if n > maxSize {
+		return Attachment{}, ErrMediaTooLarge
+	}
+
+	a := Attachment{
+		Source:      mediaURL,
+		Name:        name,
+		ContentType: mediaType(resp.Header.Get("Content-Type")),
+		Size:        n,
+		SHA256:      hex.EncodeToString(h.Sum(nil)),
+	}
+	if a.ContentType == "" || a.ContentType == "application/octet-stream" {
+		head := make([]byte, 512)
+		k, _ := f.ReadAt(head, 0)
+		a.ContentType = mediaType(http.DetectContentType(head[:k]))
+	}
+	if !d.allowed(a.ContentType) {
+		return Attachment{}, fmt.Errorf("%w: %s", ErrMediaType, a.ContentType)
+	}
+	if strings.HasPrefix(a.ContentType, "image/") {
+		if c, _, err := image.DecodeConfig(io.NewSectionReader(f, 0, n)); err == nil {
+			a.Width, a.Height = c.Width, c.Height
+		}
+	}
+
+	if a.URL, err = sink.Put(name, a.ContentType, io.NewSectionReader(f, 0, n)); err != nil {
+		return Attachment{}, err
+	}
+	return a, nil
+}
+
+// allowed reports whether content type matches ContentTypes
+func (d *Downloader) allowed(contentType string) bool {
+	if len(d.ContentTypes) == 0 {
+		return true
+	}
+	for _, t := range d.ContentTypes {
+		if t == contentType || (strings.HasSuffix(t, "/") && strings.HasPrefix(contentType, t)) {
+			return true
+		}
+	}
+	return false
+}
+
+// mediaName is the last element of media URL path
+func mediaName(mediaURL string) string {
+	if u, err := url.Parse(mediaURL); err == nil {
+		if name := path.Base(u.Path); name != "/" && name != "." {
+			return name
+		}
+	}
+	return "media"
+}
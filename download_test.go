@@ -0,0 +1,100 @@
+package viber
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestDownloader(t *testing.T) {
+	store, _ := mediaServer(t, 0)
+	pic := pngImage(30, 20)
+	src := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(pic)
+	}))
+	defer src.Close()
+
+	v := New("key", "bot", "")
+	d := v.NewDownloader(store)
+	d.ContentTypes = []string{"image/"}
+	a, err := d.Download(context.Background(), v.NewPictureMessage("", src.URL+"/x/photo.png", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Name != "photo.png" || a.ContentType != "image/png" || a.Size != int64(len(pic)) || a.Width != 30 || a.Height != 20 || len(a.SHA256) != 64 {
+		t.Fatalf("attachment %+v", a)
+	}
+
+	d.MaxSize = 10
+	if _, err := d.DownloadURL(context.Background(), src.URL+"/photo.png", ""); !errors.Is(err, ErrMediaTooLarge) {
+		t.Fatalf("oversized media: %v", err)
+	}
+	d.MaxSize = 0
+	d.ContentTypes = []string{"video/mp4"}
+	if _, err := d.DownloadURL(context.Background(), src.URL+"/photo.png", ""); !errors.Is(err, ErrMediaType) {
+		t.Fatalf("disallowed type: %v", err)
+	}
+}
+
+func TestDownloaderIgnoresAPITimeout(t *testing.T) {
+	store, _ := mediaServer(t, 0)
+	src := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("partial "))
+		w.(http.Flusher).Flush()
+		time.Sleep(300 * time.Millisecond)
+		w.Write([]byte("media"))
+	}))
+	defer src.Close()
+
+	v := New("key", "bot", "", WithRequestTimeout(100*time.Millisecond))
+	d := v.NewDownloader(store)
+	d.Timeout = 10 * time.Second
+	a, err := d.DownloadURL(context.Background(), src.URL+"/file.txt", "")
+	if err != nil {
+		t.Fatalf("download limited by API timeout: %v", err)
+	}
+	if a.Size != int64(len("partial media")) {
+		t.Fatalf("attachment size %d", a.Size)
+	}
+
+	d.Timeout = 100 * time.Millisecond
+	if _, err := d.DownloadURL(context.Background(), src.URL+"/file.txt", ""); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("download past Timeout: %v", err)
+	}
+}
+
+// countingTransport counts requests passed to http.DefaultTransport
+type countingTransport struct{ n int }
+
+func (c *countingTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	c.n++
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+func TestDownloaderTransport(t *testing.T) {
+	store, _ := mediaServer(t, 0)
+	src := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("media"))
+	}))
+	defer src.Close()
+
+	api, media := &countingTransport{}, &countingTransport{}
+	v := New("key", "bot", "", WithTransport(api))
+	if _, err := v.NewDownloader(store).DownloadURL(context.Background(), src.URL+"/file.txt", ""); err != nil {
+		t.Fatal(err)
+	}
+	if api.n != 0 {
+		t.Fatal("media fetched with API transport")
+	}
+
+	v = New("key", "bot", "", WithTransport(api), WithMediaTransport(media))
+	if _, err := v.NewDownloader(store).DownloadURL(context.Background(), src.URL+"/file.txt", ""); err != nil {
+		t.Fatal(err)
+	}
+	if api.n != 0 || media.n != 1 {
+		t.Fatalf("api transport %d, media transport %d requests", api.n, media.n)
+	}
+}
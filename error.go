@@ -1,5 +1,7 @@
 package viber
 
+import "errors"
+
 // Error from Viber
 type Error struct {
 	Status        int
@@ -20,3 +22,63 @@ func ErrorStatus(e interface{}) int {
 	}
 	return -1
 }
+
+// Webhook request errors
+var (
+	ErrBodyTooLarge = errors.New("viber: request body too large")
+	ErrSignature    = errors.New("viber: X-Viber-Content-Signature doesn't match")
+	ErrStaleEvent   = errors.New("viber: event timestamp outside of allowed window")
+)
+
+// WebhookError is reported to OnError when webhook request is rejected or can't be processed
+type WebhookError struct {
+	StatusCode int    // HTTP status code sent as response
+	Body       []byte // raw request body
+	Err        error
+}
+
+// Error interface function
+func (e *WebhookError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns underlying error
+func (e *WebhookError) Unwrap() error {
+	return e.Err
+}
+
+// Viber API status codes
+// https://developers.viber.com/docs/api/rest-bot-api/#error-codes
+const (
+	StatusOK                         = 0
+	StatusInvalidURL                 = 1
+	StatusInvalidAuthToken           = 2
+	StatusBadData                    = 3
+	StatusMissingData                = 4
+	StatusReceiverNotRegistered      = 5
+	StatusReceiverNotSubscribed      = 6
+	StatusPublicAccountBlocked       = 7
+	StatusPublicAccountNotFound      = 8
+	StatusPublicAccountSuspended     = 9
+	StatusWebhookNotSet              = 10
+	StatusReceiverNoSuitableDevice   = 11
+	StatusTooManyRequests            = 12
+	StatusAPIVersionNotSupported     = 13
+	StatusIncompatibleWithVersion    = 14
+	StatusPublicAccountNotAuthorized = 15
+	StatusInchatReplyNotAllowed      = 16
+	StatusPublicAccountNotInline     = 17
+	StatusNoPublicChat               = 18
+	StatusCannotSendBroadcast        = 19
+	StatusBroadcastNotAllowed        = 20
+)
+
+// temporary reports whether API call failed with error worth retrying,
+// network error or Viber throttling
+func temporary(err error) bool {
+	switch ErrorStatus(err) {
+	case -1, StatusTooManyRequests:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,26 @@
+package viber
+
+import "encoding/json"
+
+// ClientStatusEvent is sent by Viber when status of user's client changes.
+// Fields not decoded by the package are available in Raw event body.
+type ClientStatusEvent struct {
+	Event        EventType       `json:"event"`
+	Timestamp    Timestamp       `json:"timestamp"`
+	MessageToken uint64          `json:"message_token,omitempty"`
+	UserID       string          `json:"user_id,omitempty"`
+	Status       string          `json:"status,omitempty"`
+	Raw          json.RawMessage `json:"-"`
+}
+
+// ActionEvent is sent by Viber when user performs an action which is not a message.
+// Fields not decoded by the package are available in Raw event body.
+type ActionEvent struct {
+	Event        EventType       `json:"event"`
+	Timestamp    Timestamp       `json:"timestamp"`
+	MessageToken uint64          `json:"message_token,omitempty"`
+	UserID       string          `json:"user_id,omitempty"`
+	User         *User           `json:"user,omitempty"`
+	Action       json.RawMessage `json:"action,omitempty"`
+	Raw          json.RawMessage `json:"-"`
+}
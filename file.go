@@ -0,0 +1,19 @@
+package viber
+
+// NewFileMessage for viber, size of file in bytes and file name with extension are mandatory
+func (v *Viber) NewFileMessage(url string, fileName string, size uint) *FileMessage {
+	return &FileMessage{
+		TextMessage: TextMessage{
+			Sender: v.Sender,
+			Type:   TypeFileMessage,
+		},
+		Media:    url,
+		Size:     size,
+		FileName: fileName,
+	}
+}
+
+// SendFileMessage to receiver, returns message token
+func (v *Viber) SendFileMessage(receiver string, url string, fileName string, size uint) (token uint64, err error) {
+	return v.SendMessage(receiver, v.NewFileMessage(url, fileName, size))
+}
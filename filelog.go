@@ -0,0 +1,244 @@
+package viber
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// fileLog is append-only file of JSON records keyed by id, later records override earlier ones.
+// Log is compacted to the latest state of live records when opened, and while in use
+// once it holds more than compactMinRecords of which most are overridden or deleted.
+type fileLog struct {
+	mu   sync.Mutex
+	path string
+	f    *os.File
+
+	// latest record by id in order of first appearance, deleted ones are dropped on compaction
+	records []logRecord
+	index   map[string]int
+	live    int
+	lines   int // records written to the file
+}
+
+type logRecord struct {
+	ID      string          `json:"id"`
+	Deleted bool            `json:"deleted,omitempty"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+// compactMinRecords in the log before it is compacted while in use
+const compactMinRecords = 1000
+
+// openFileLog replays log file at path and returns live records in order of their first appearance
+func openFileLog(path string) (*fileLog, []json.RawMessage, error) {
+	l := &fileLog{path: path, index: make(map[string]int)}
+	if err := l.replay(); err != nil {
+		return nil, nil, err
+	}
+	// partially written last line is dropped by compaction as well
+	if err := l.compact(); err != nil {
+		return nil, nil, err
+	}
+
+	data := make([]json.RawMessage, len(l.records))
+	for i, r := range l.records {
+		data[i] = r.Data
+	}
+	return l, data, nil
+}
+
+// replay records from log file, missing file is empty log
+func (l *fileLog) replay() error {
+	f, err := os.Open(l.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	s.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for s.Scan() {
+		var r logRecord
+		if err := json.Unmarshal(s.Bytes(), &r); err != nil {
+			// torn write of the last record after crash
+			continue
+		}
+		l.apply(r)
+	}
+	return s.Err()
+}
+
+// apply record to the latest state, l.mu must be held
+func (l *fileLog) apply(r logRecord) {
+	i, ok := l.index[r.ID]
+	switch {
+	case r.Deleted && ok:
+		if !l.records[i].Deleted {
+			l.live--
+		}
+		l.records[i].Deleted = true
+	case ok:
+		if l.records[i].Deleted {
+			l.live++
+		}
+		l.records[i] = r
+	case !r.Deleted:
+		l.index[r.ID] = len(l.records)
+		l.records = append(l.records, r)
+		l.live++
+	}
+}
+
+// compact writes live records to temp file which replaces the log, l.mu must be held.
+// Temp file is opened for appending, so it stays open as the log after rename.
+func (l *fileLog) compact() error {
+	live := make([]logRecord, 0, l.live)
+	index := make(map[string]int, l.live)
+	for _, r := range l.records {
+		if !r.Deleted {
+			index[r.ID] = len(live)
+			live = append(live, r)
+		}
+	}
+
+	tmp := l.path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for _, r := range live {
+		if err := enc.Encode(r); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := os.Rename(tmp, l.path); err != nil {
+		f.Close()
+		return err
+	}
+	syncDir(filepath.Dir(l.path))
+
+	if l.f != nil {
+		l.f.Close()
+	}
+	l.f = f
+	l.records, l.index, l.lines = live, index, len(live)
+	return nil
+}
+
+// put appends record with data for id and syncs it to disk
+func (l *fileLog) put(id string, data interface{}) error {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	return l.append(logRecord{ID: id, Data: b})
+}
+
+// delete appends deletion record for id
+func (l *fileLog) delete(id string) error {
+	return l.append(logRecord{ID: id, Deleted: true})
+}
+
+func (l *fileLog) append(r logRecord) error {
+	b, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, err := l.f.Write(b); err != nil {
+		return err
+	}
+	if err := l.f.Sync(); err != nil {
+		return err
+	}
+	l.apply(r)
+	l.lines++
+
+	// every send adds records, so log is compacted once overridden and deleted records dominate.
+	// Record is already saved, so failed compaction is only retried with the next record.
+	if l.lines >= compactMinRecords && l.lines > 2*l.live {
+		l.compact()
+	}
+	return nil
+}
+
+// close log file
+func (l *fileLog) close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.f.Close()
+}
+
+// syncDir so renamed file survives crash, errors are ignored since not all platforms support it
+func syncDir(dir string) {
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+}
+
+// fileStore keeps records of type T in fileLog, it backs FileQueueStore and FileJobStore
+type fileStore[T any] struct {
+	log     *fileLog
+	id      func(r T) string
+	records []T
+}
+
+// openFileStore opens or creates store file at path, id returns the key of record
+func openFileStore[T any](path string, id func(r T) string) (*fileStore[T], error) {
+	l, data, err := openFileLog(path)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &fileStore[T]{log: l, id: id}
+	for _, d := range data {
+		var r T
+		if err := json.Unmarshal(d, &r); err != nil {
+			l.close()
+			return nil, err
+		}
+		s.records = append(s.records, r)
+	}
+	return s, nil
+}
+
+// Save record state to file
+func (s *fileStore[T]) Save(r T) error {
+	return s.log.put(s.id(r), r)
+}
+
+// Delete record from file
+func (s *fileStore[T]) Delete(id string) error {
+	return s.log.delete(id)
+}
+
+// Load records read from file when store was opened
+func (s *fileStore[T]) Load() ([]T, error) {
+	return s.records, nil
+}
+
+// Close store file
+func (s *fileStore[T]) Close() error {
+	return s.log.close()
+}
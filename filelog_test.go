@@ -0,0 +1,78 @@
+package viber
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+type testRecord struct {
+	ID    string `json:"id"`
+	Value int    `json:"value"`
+}
+
+func openTestStore(t *testing.T, path string) *fileStore[testRecord] {
+	s, err := openFileStore(path, func(r testRecord) string { return r.ID })
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestFileStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store.log")
+	s := openTestStore(t, path)
+	s.Save(testRecord{"a", 1})
+	s.Save(testRecord{"b", 1})
+	s.Save(testRecord{"a", 2})
+	s.Delete("b")
+	s.Save(testRecord{"c", 1})
+	s.Close()
+
+	// torn write of the last record is dropped
+	f, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0600)
+	f.WriteString(`{"id":"d","data":{"id":"d","va`)
+	f.Close()
+
+	s = openTestStore(t, path)
+	records, _ := s.Load()
+	if len(records) != 2 || records[0] != (testRecord{"a", 2}) || records[1] != (testRecord{"c", 1}) {
+		t.Fatalf("records %+v", records)
+	}
+	s.Save(testRecord{"e", 1})
+	s.Close()
+
+	s = openTestStore(t, path)
+	defer s.Close()
+	if records, _ := s.Load(); len(records) != 3 {
+		t.Fatalf("records after compaction %+v", records)
+	}
+}
+
+func TestFileStoreCompactsWhileOpen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store.log")
+	s := openTestStore(t, path)
+	s.Save(testRecord{"keep", 1})
+	for i := 0; i < 3*compactMinRecords; i++ {
+		id := strconv.Itoa(i)
+		s.Save(testRecord{id, 1})
+		s.Save(testRecord{id, 2})
+		s.Delete(id)
+	}
+	s.Save(testRecord{"last", 1})
+	s.Close()
+
+	b, _ := os.ReadFile(path)
+	if n := bytes.Count(b, []byte("\n")); n >= compactMinRecords {
+		t.Fatalf("log has %d records, not compacted while open", n)
+	}
+
+	s = openTestStore(t, path)
+	defer s.Close()
+	records, _ := s.Load()
+	if len(records) != 2 || records[0] != (testRecord{"keep", 1}) || records[1] != (testRecord{"last", 1}) {
+		t.Fatalf("records after compaction %+v", records)
+	}
+}
@@ -0,0 +1,340 @@
+package viber
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Filter selects subscribers by expression over their details and tags, for example
+//
+//	country == "RS" && (language in ["sr", "en"] || tag == "vip") && api_version >= 3
+//
+// Fields: country, language, os (primary device OS), api_version, viber_version, device_type, tag.
+// Operators: ==, !=, <, <=, >, >= (numeric for api_version), ~ (contains), in [list],
+// combined with &&, ||, ! and parentheses. String comparison is case insensitive.
+// Field tag matches if any of subscriber's tags matches. Empty expression matches everyone.
+type Filter struct {
+	expr string
+	root filterNode
+}
+
+// ParseFilter parses filter expression
+func ParseFilter(expr string) (*Filter, error) {
+	f := &Filter{expr: expr}
+	if strings.TrimSpace(expr) == "" {
+		return f, nil
+	}
+
+	tokens, err := lexFilter(expr)
+	if err != nil {
+		return nil, fmt.Errorf("viber: filter %q: %v", expr, err)
+	}
+	p := &filterParser{tokens: tokens}
+	root, err := p.parseOr()
+	if err == nil && p.pos < len(p.tokens) {
+		err = fmt.Errorf("unexpected %q", p.tokens[p.pos].text)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("viber: filter %q: %v", expr, err)
+	}
+	f.root = root
+	return f, nil
+}
+
+// Match reports whether subscriber s matches the filter
+func (f *Filter) Match(s Subscriber) bool {
+	if f.root == nil {
+		return true
+	}
+	return f.root.match(s)
+}
+
+// String returns filter expression
+func (f *Filter) String() string {
+	return f.expr
+}
+
+type filterNode interface {
+	match(s Subscriber) bool
+}
+
+type andNode struct{ l, r filterNode }
+type orNode struct{ l, r filterNode }
+type notNode struct{ n filterNode }
+type cmpNode struct {
+	field  string
+	op     string
+	values []string
+}
+
+func (n andNode) match(s Subscriber) bool { return n.l.match(s) && n.r.match(s) }
+func (n orNode) match(s Subscriber) bool  { return n.l.match(s) || n.r.match(s) }
+func (n notNode) match(s Subscriber) bool { return !n.n.match(s) }
+
+func (n cmpNode) match(s Subscriber) bool {
+	var fields []string
+	switch n.field {
+	case "country":
+		fields = []string{s.Country}
+	case "language":
+		fields = []string{s.Language}
+	case "os":
+		fields = []string{s.PrimaryDeviceOs}
+	case "api_version":
+		fields = []string{strconv.Itoa(s.APIVersion)}
+	case "viber_version":
+		fields = []string{s.ViberVersion}
+	case "device_type":
+		fields = []string{s.DeviceType}
+	case "tag":
+		fields = s.Tags
+	}
+
+	if n.op == "!=" {
+		for _, f := range fields {
+			if compare(f, "==", n.values[0]) {
+				return false
+			}
+		}
+		return true
+	}
+
+	for _, f := range fields {
+		for _, v := range n.values {
+			if compare(f, n.op, v) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// compare field value with filter value, numerically if both are numbers
+func compare(field, op, value string) bool {
+	if op == "~" {
+		return strings.Contains(strings.ToLower(field), strings.ToLower(value))
+	}
+
+	c := 0
+	fn, ferr := strconv.ParseFloat(field, 64)
+	vn, verr := strconv.ParseFloat(value, 64)
+	if ferr == nil && verr == nil {
+		switch {
+		case fn < vn:
+			c = -1
+		case fn > vn:
+			c = 1
+		}
+	} else {
+		c = strings.Compare(strings.ToLower(field), strings.ToLower(value))
+	}
+
+	switch op {
+	case "==", "in":
+		return c == 0
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	case ">=":
+		return c >= 0
+	}
+	return false
+}
+
+var filterFields = map[string]bool{
+	"country": true, "language": true, "os": true, "api_version": true,
+	"viber_version": true, "device_type": true, "tag": true,
+}
+
+type filterToken struct {
+	kind byte // 'i' identifier, 's' string or number, 'o' operator or punctuation
+	text string
+}
+
+func lexFilter(expr string) ([]filterToken, error) {
+	var tokens []filterToken
+	r := []rune(expr)
+	for i := 0; i < len(r); {
+		c := r[i]
+		switch {
+		case unicode.IsSpace(c):
+			i++
+
+		case c == '"' || c == '\'':
+			j := i + 1
+			for j < len(r) && r[j] != c {
+				j++
+			}
+			if j == len(r) {
+				return nil, fmt.Errorf("unterminated string")
+			}
+			tokens = append(tokens, filterToken{'s', string(r[i+1 : j])})
+			i = j + 1
+
+		case unicode.IsDigit(c) || c == '-' || c == '.':
+			j := i + 1
+			for j < len(r) && (unicode.IsDigit(r[j]) || r[j] == '.') {
+				j++
+			}
+			if !strings.ContainsFunc(string(r[i:j]), unicode.IsDigit) {
+				return nil, fmt.Errorf("invalid number %q", string(r[i:j]))
+			}
+			tokens = append(tokens, filterToken{'s', string(r[i:j])})
+			i = j
+
+		case unicode.IsLetter(c) || c == '_':
+			j := i + 1
+			for j < len(r) && (unicode.IsLetter(r[j]) || unicode.IsDigit(r[j]) || r[j] == '_') {
+				j++
+			}
+			tokens = append(tokens, filterToken{'i', strings.ToLower(string(r[i:j]))})
+			i = j
+
+		default:
+			op := string(c)
+			if i+1 < len(r) {
+				switch two := string(r[i : i+2]); two {
+				case "==", "!=", "<=", ">=", "&&", "||":
+					op = two
+				}
+			}
+			switch op {
+			case "==", "!=", "<=", ">=", "&&", "||", "<", ">", "~", "!", "(", ")", "[", "]", ",":
+			default:
+				return nil, fmt.Errorf("unexpected %q", op)
+			}
+			tokens = append(tokens, filterToken{'o', op})
+			i += len([]rune(op))
+		}
+	}
+	return tokens, nil
+}
+
+type filterParser struct {
+	tokens []filterToken
+	pos    int
+}
+
+func (p *filterParser) peek() (filterToken, bool) {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos], true
+	}
+	return filterToken{}, false
+}
+
+// accept next token if it is operator or keyword op
+func (p *filterParser) accept(op string) bool {
+	if t, ok := p.peek(); ok && t.kind != 's' && t.text == op {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *filterParser) parseOr() (filterNode, error) {
+	n, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("||") || p.accept("or") {
+		r, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		n = orNode{n, r}
+	}
+	return n, nil
+}
+
+func (p *filterParser) parseAnd() (filterNode, error) {
+	n, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("&&") || p.accept("and") {
+		r, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		n = andNode{n, r}
+	}
+	return n, nil
+}
+
+func (p *filterParser) parseUnary() (filterNode, error) {
+	if p.accept("!") || p.accept("not") {
+		n, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{n}, nil
+	}
+	if p.accept("(") {
+		n, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.accept(")") {
+			return nil, fmt.Errorf("missing )")
+		}
+		return n, nil
+	}
+	return p.parseCmp()
+}
+
+func (p *filterParser) parseCmp() (filterNode, error) {
+	t, ok := p.peek()
+	if !ok {
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+	if t.kind != 'i' || !filterFields[t.text] {
+		return nil, fmt.Errorf("unknown field %q", t.text)
+	}
+	p.pos++
+	n := cmpNode{field: t.text}
+
+	op, ok := p.peek()
+	if !ok || op.kind == 's' {
+		return nil, fmt.Errorf("missing operator after %s", n.field)
+	}
+	p.pos++
+	n.op = op.text
+
+	switch n.op {
+	case "in":
+		if !p.accept("[") {
+			return nil, fmt.Errorf("in requires [list]")
+		}
+		for {
+			v, ok := p.peek()
+			if !ok || v.kind != 's' {
+				return nil, fmt.Errorf("invalid list for %s", n.field)
+			}
+			p.pos++
+			n.values = append(n.values, v.text)
+			if p.accept("]") {
+				break
+			}
+			if !p.accept(",") {
+				return nil, fmt.Errorf("missing , or ] in list")
+			}
+		}
+
+	case "==", "!=", "<", "<=", ">", ">=", "~":
+		v, ok := p.peek()
+		if !ok || v.kind != 's' {
+			return nil, fmt.Errorf("missing value after %s %s", n.field, n.op)
+		}
+		p.pos++
+		n.values = []string{v.text}
+
+	default:
+		return nil, fmt.Errorf("unknown operator %q", n.op)
+	}
+	return n, nil
+}
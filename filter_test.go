@@ -0,0 +1,53 @@
+package viber
+
+import "testing"
+
+func TestFilter(t *testing.T) {
+	sub := Subscriber{
+		User: User{ID: "u1", Country: "RS", Language: "sr", PrimaryDeviceOs: "Android 13", APIVersion: 7},
+		Tags: []string{"vip", "beta"},
+	}
+
+	tests := []struct {
+		expr  string
+		match bool
+	}{
+		{"", true},
+		{`country == "rs"`, true},
+		{`country != "RS"`, false},
+		{`api_version >= 3 && api_version < 10`, true},
+		{`api_version > 7`, false},
+		{`language in ["en", "sr"]`, true},
+		{`os ~ "android"`, true},
+		{`tag == "vip"`, true},
+		{`tag != "vip"`, false},
+		{`!(country == "RS") || tag == "beta"`, true},
+		{`not country == "RS" and tag == "beta"`, false},
+	}
+	for _, tt := range tests {
+		f, err := ParseFilter(tt.expr)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.expr, err)
+		}
+		if got := f.Match(sub); got != tt.match {
+			t.Errorf("%s: got %v, want %v", tt.expr, got, tt.match)
+		}
+	}
+}
+
+func TestFilterErrors(t *testing.T) {
+	for _, expr := range []string{
+		`api_version >= -`,
+		`api_version >= .`,
+		`country ==`,
+		`city == "Belgrade"`,
+		`(country == "RS"`,
+		`language in ["sr"`,
+		`country = "RS"`,
+		`country == "RS" &&`,
+	} {
+		if _, err := ParseFilter(expr); err == nil {
+			t.Errorf("%s: expected error", expr)
+		}
+	}
+}
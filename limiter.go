@@ -0,0 +1,53 @@
+package viber
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Limiter limits rate of API calls made by Queue and other senders.
+// golang.org/x/time/rate.Limiter satisfies the interface.
+type Limiter interface {
+	// Wait blocks until next call is allowed or ctx is done
+	Wait(ctx context.Context) error
+}
+
+// intervalLimiter allows one call per interval
+type intervalLimiter struct {
+	interval time.Duration
+
+	mu   sync.Mutex
+	next time.Time
+}
+
+// Every returns Limiter which allows one call per interval
+func Every(interval time.Duration) Limiter {
+	return &intervalLimiter{interval: interval}
+}
+
+// Wait blocks until next call is allowed or ctx is done
+func (l *intervalLimiter) Wait(ctx context.Context) error {
+	l.mu.Lock()
+	now := time.Now()
+	at := l.next
+	if at.Before(now) {
+		at = now
+	}
+	l.next = at.Add(l.interval)
+	l.mu.Unlock()
+
+	d := time.Until(at)
+	if d <= 0 {
+		return nil
+	}
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
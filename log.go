@@ -0,0 +1,86 @@
+package viber
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log/slog"
+)
+
+// fallbackLogger writes structured logs through global Log, used when Viber has no Logger set
+var fallbackLogger = slog.New(logHandler{slog.NewTextHandler(logWriter{}, &slog.HandlerOptions{Level: slog.LevelDebug})})
+
+// logWriter writes log records to global Log
+type logWriter struct{}
+
+func (logWriter) Write(p []byte) (int, error) {
+	Log.Print(string(p))
+	return len(p), nil
+}
+
+// logHandler skips formatting of records while global Log is discarding output
+type logHandler struct {
+	slog.Handler
+}
+
+func (h logHandler) Enabled(ctx context.Context, l slog.Level) bool {
+	return Log.Writer() != io.Discard && h.Handler.Enabled(ctx, l)
+}
+
+func (h logHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return logHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h logHandler) WithGroup(name string) slog.Handler {
+	return logHandler{h.Handler.WithGroup(name)}
+}
+
+// logger of v, or fallback logger writing to global Log
+func (v *Viber) logger() *slog.Logger {
+	if v.Logger != nil {
+		return v.Logger
+	}
+	return fallbackLogger
+}
+
+// userAttr for logging user id, hashed if Redact is set
+func (v *Viber) userAttr(id string) slog.Attr {
+	return slog.String("user_id", v.redactUser(id))
+}
+
+// redactUser returns user id, or its short hash if Redact is set
+func (v *Viber) redactUser(id string) string {
+	if v.Redact && id != "" {
+		h := sha256.Sum256([]byte(id))
+		return hex.EncodeToString(h[:6])
+	}
+	return id
+}
+
+// logBody logs raw request or response body on debug level, unless Redact is set
+// since body contains user names, phone numbers and message texts
+func (v *Viber) logBody(msg string, b []byte) {
+	if v.Redact {
+		return
+	}
+	v.logger().Debug(msg, slog.String("body", string(b)))
+}
+
+// userID of event, taken from sender or user object when not set directly
+func (e *event) userID() string {
+	if e.UserID != "" {
+		return e.UserID
+	}
+
+	var u struct {
+		ID string `json:"id"`
+	}
+	if len(e.Sender) > 0 {
+		json.Unmarshal(e.Sender, &u)
+	} else if len(e.User) > 0 {
+		json.Unmarshal(e.User, &u)
+	}
+	return u.ID
+}
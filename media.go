@@ -0,0 +1,102 @@
+package viber
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+// probeTimeout of HEAD request which checks remote media
+const probeTimeout = 10 * time.Second
+
+// defaultMediaClient fetches media from other hosts. It has no timeout, requests are limited by context deadline,
+// so timeout and transport of API client apply only to Viber API calls.
+var defaultMediaClient = &http.Client{Transport: http.DefaultTransport.(*http.Transport).Clone()}
+
+// mediaHTTPClient used for media downloads and checks
+func (v *Viber) mediaHTTPClient() *http.Client {
+	if v.mediaClient != nil {
+		return v.mediaClient
+	}
+	return defaultMediaClient
+}
+
+// mediaInfo of local file or remote media
+type mediaInfo struct {
+	size        int64
+	contentType string
+	ext         string
+}
+
+// probeMedia returns size, content type and extension of local file or http(s) URL.
+// Remote media is checked with HEAD request, content of local file is sniffed for its type.
+func (v *Viber) probeMedia(source string) (mediaInfo, error) {
+	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+		return v.probeURL(source)
+	}
+	return probeFile(source)
+}
+
+func (v *Viber) probeURL(source string) (mediaInfo, error) {
+	u, err := url.Parse(source)
+	if err != nil {
+		return mediaInfo{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(v.Context(), probeTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "HEAD", source, nil)
+	if err != nil {
+		return mediaInfo{}, err
+	}
+	resp, err := v.mediaHTTPClient().Do(req)
+	if err != nil {
+		return mediaInfo{}, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return mediaInfo{}, fmt.Errorf("unreachable, %s", resp.Status)
+	}
+
+	return mediaInfo{
+		size:        resp.ContentLength,
+		contentType: mediaType(resp.Header.Get("Content-Type")),
+		ext:         strings.ToLower(path.Ext(u.Path)),
+	}, nil
+}
+
+func probeFile(name string) (mediaInfo, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return mediaInfo{}, err
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return mediaInfo{}, err
+	}
+	head := make([]byte, 512)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return mediaInfo{}, err
+	}
+
+	return mediaInfo{
+		size:        fi.Size(),
+		contentType: mediaType(http.DetectContentType(head[:n])),
+		ext:         strings.ToLower(path.Ext(name)),
+	}, nil
+}
+
+// mediaType strips parameters from content type
+func mediaType(contentType string) string {
+	t, _, _ := strings.Cut(contentType, ";")
+	return strings.ToLower(strings.TrimSpace(t))
+}
@@ -0,0 +1,260 @@
+package viber
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MediaStore stores outbound media and returns public URL Viber can download it from
+type MediaStore interface {
+	Put(name, contentType string, r io.Reader) (url string, err error)
+}
+
+// DiskMediaStore keeps media in local directory and serves it as http.Handler.
+// Media URLs contain random id so they can't be guessed, and expire after store TTL.
+type DiskMediaStore struct {
+	dir     string
+	baseURL string
+	ttl     time.Duration
+
+	mu      sync.Mutex
+	cleaned time.Time
+}
+
+// mediaMeta is saved next to each media file
+type mediaMeta struct {
+	Name        string    `json:"name"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	Expires     time.Time `json:"expires,omitempty"`
+}
+
+// inlineTypes are served inline by DiskMediaStore, other media is served as attachment
+var inlineTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+	"video/mp4":  true,
+}
+
+// mediaCleanupInterval between removals of expired media
+const mediaCleanupInterval = time.Minute
+
+// NewDiskMediaStore stores media in dir, creating it if needed. Store must be served at baseURL,
+// e.g. with http.Handle("/media/", store) for baseURL "https://example.com/media/".
+// Media expires after ttl, zero ttl keeps media forever.
+func NewDiskMediaStore(dir, baseURL string, ttl time.Duration) (*DiskMediaStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &DiskMediaStore{dir: dir, baseURL: strings.TrimSuffix(baseURL, "/"), ttl: ttl}, nil
+}
+
+// Put stores media read from r under name and returns its URL.
+// Content type is detected from content if empty.
+func (s *DiskMediaStore) Put(name, contentType string, r io.Reader) (string, error) {
+	s.cleanupIfDue()
+
+	br := bufio.NewReader(r)
+	if contentType == "" {
+		head, _ := br.Peek(512)
+		contentType = http.DetectContentType(head)
+	}
+
+	id := newID()
+	file := filepath.Join(s.dir, id)
+	f, err := os.OpenFile(file, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return "", err
+	}
+	n, err := io.Copy(f, br)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(file)
+		return "", err
+	}
+
+	name = path.Base("/" + filepath.ToSlash(name))
+	if name == "/" {
+		name = "media"
+	}
+	meta := mediaMeta{Name: name, ContentType: contentType, Size: n}
+	if s.ttl > 0 {
+		meta.Expires = time.Now().Add(s.ttl)
+	}
+	b, _ := json.Marshal(meta)
+	if err := os.WriteFile(file+".json", b, 0600); err != nil {
+		os.Remove(file)
+		return "", err
+	}
+
+	return s.baseURL + "/" + id + "/" + url.PathEscape(name), nil
+}
+
+// ServeHTTP serves stored media at URLs returned by Put
+func (s *DiskMediaStore) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" && r.Method != "HEAD" {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	// url path ends with /id/name
+	dir, _ := path.Split(strings.TrimSuffix(r.URL.Path, "/"))
+	id := path.Base(dir)
+	meta, ok := s.meta(id)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	f, err := os.Open(filepath.Join(s.dir, id))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// media may come from users, so anything but pictures and videos Viber accepts is served
+	// as download, and browsers must not sniff it into HTML
+	w.Header().Set("Content-Type", meta.ContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	if !inlineTypes[meta.ContentType] {
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": meta.Name}))
+	}
+	http.ServeContent(w, r, meta.Name, fi.ModTime(), f)
+}
+
+// meta of live media with id, expired media is removed
+func (s *DiskMediaStore) meta(id string) (mediaMeta, bool) {
+	if b, err := hex.DecodeString(id); err != nil || len(b) != 16 {
+		return mediaMeta{}, false
+	}
+
+	var meta mediaMeta
+	b, err := os.ReadFile(filepath.Join(s.dir, id+".json"))
+	if err != nil || json.Unmarshal(b, &meta) != nil {
+		return mediaMeta{}, false
+	}
+	if !meta.Expires.IsZero() && time.Now().After(meta.Expires) {
+		s.remove(id)
+		return mediaMeta{}, false
+	}
+	return meta, true
+}
+
+// Cleanup removes expired media. It is called by Put at most once a minute.
+func (s *DiskMediaStore) Cleanup() error {
+	files, err := filepath.Glob(filepath.Join(s.dir, "*.json"))
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		// meta removes expired media
+		s.meta(strings.TrimSuffix(filepath.Base(f), ".json"))
+	}
+	return nil
+}
+
+func (s *DiskMediaStore) cleanupIfDue() {
+	if s.ttl <= 0 {
+		return
+	}
+	s.mu.Lock()
+	due := time.Since(s.cleaned) >= mediaCleanupInterval
+	if due {
+		s.cleaned = time.Now()
+	}
+	s.mu.Unlock()
+	if due {
+		s.Cleanup()
+	}
+}
+
+func (s *DiskMediaStore) remove(id string) {
+	os.Remove(filepath.Join(s.dir, id))
+	os.Remove(filepath.Join(s.dir, id+".json"))
+}
+
+// ErrNoMediaStore is returned by upload helpers if Viber MediaStore is not set
+var ErrNoMediaStore = errors.New("viber: media store not set")
+
+// countingReader counts bytes read
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// upload media to MediaStore, returns its URL and size
+func (v *Viber) upload(name, contentType string, r io.Reader) (string, int64, error) {
+	if v.MediaStore == nil {
+		return "", 0, ErrNoMediaStore
+	}
+	c := &countingReader{r: r}
+	u, err := v.MediaStore.Put(name, contentType, c)
+	return u, c.n, err
+}
+
+// UploadPictureMessage stores picture read from r in MediaStore and returns picture message with its URL.
+// Thumbnail of the picture is generated and uploaded as well, message is returned without thumbnail
+// if picture can't be decoded. Use bytes.NewReader to upload generated picture.
+func (v *Viber) UploadPictureMessage(msg string, name string, r io.Reader) (*PictureMessage, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	u, _, err := v.upload(name, "", bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+
+	m := v.NewPictureMessage(msg, u, "")
+	if err := v.AttachThumbnail(m, bytes.NewReader(b)); err != nil {
+		v.logger().Debug("thumbnail not attached", slog.String("media", u), slog.String("error", err.Error()))
+	}
+	return m, nil
+}
+
+// UploadVideoMessage stores video read from r in MediaStore and returns video message with its URL and size
+func (v *Viber) UploadVideoMessage(name string, r io.Reader, duration uint) (*VideoMessage, error) {
+	u, size, err := v.upload(name, "", r)
+	if err != nil {
+		return nil, err
+	}
+	return v.NewVideoMessage(u, "", uint(size), duration), nil
+}
+
+// UploadFileMessage stores file read from r in MediaStore and returns file message with its URL, name and size
+func (v *Viber) UploadFileMessage(name string, r io.Reader) (*FileMessage, error) {
+	u, size, err := v.upload(name, "", r)
+	if err != nil {
+		return nil, err
+	}
+	return v.NewFileMessage(u, path.Base("/"+filepath.ToSlash(name)), uint(size)), nil
+}
@@ -0,0 +1,104 @@
+package viber
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// mediaServer serves store at /media/ of test server
+func mediaServer(t *testing.T, ttl time.Duration) (*DiskMediaStore, *httptest.Server) {
+	mux := http.NewServeMux()
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	store, err := NewDiskMediaStore(t.TempDir(), srv.URL+"/media/", ttl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux.Handle("/media/", store)
+	return store, srv
+}
+
+func pngImage(w, h int) []byte {
+	var buf bytes.Buffer
+	png.Encode(&buf, image.NewGray(image.Rect(0, 0, w, h)))
+	return buf.Bytes()
+}
+
+func TestDiskMediaStore(t *testing.T) {
+	store, _ := mediaServer(t, time.Hour)
+
+	u, err := store.Put("chart.png", "", bytes.NewReader(pngImage(10, 10)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.Get(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "image/png" {
+		t.Fatalf("picture served with %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	if resp.Header.Get("Content-Disposition") != "" || resp.Header.Get("X-Content-Type-Options") != "nosniff" {
+		t.Fatalf("picture headers %v", resp.Header)
+	}
+
+	u, _ = store.Put("page.html", "text/html", strings.NewReader("<script>alert(1)</script>"))
+	resp, _ = http.Get(u)
+	resp.Body.Close()
+	if !strings.HasPrefix(resp.Header.Get("Content-Disposition"), "attachment") || resp.Header.Get("X-Content-Type-Options") != "nosniff" {
+		t.Fatalf("html served inline, headers %v", resp.Header)
+	}
+
+	// guessed id
+	resp, _ = http.Get(u[:strings.LastIndex(u, "/media/")] + "/media/0123456789abcdef0123456789abcdef/page.html")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("unknown id served with %d", resp.StatusCode)
+	}
+}
+
+func TestDiskMediaStoreExpiry(t *testing.T) {
+	store, _ := mediaServer(t, 10*time.Millisecond)
+	u, _ := store.Put("a.txt", "", strings.NewReader("hello"))
+	time.Sleep(20 * time.Millisecond)
+	resp, _ := http.Get(u)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("expired media served with %d", resp.StatusCode)
+	}
+}
+
+func TestUploadPictureMessage(t *testing.T) {
+	store, _ := mediaServer(t, 0)
+	v := New("key", "bot", "")
+	if _, err := v.UploadPictureMessage("", "a.png", bytes.NewReader(nil)); err != ErrNoMediaStore {
+		t.Fatalf("upload without store: %v", err)
+	}
+	v.MediaStore = store
+
+	m, err := v.UploadPictureMessage("chart", "chart.png", bytes.NewReader(pngImage(1200, 600)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := v.Preflight(m); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.Get(m.Thumbnail)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	b, _ := io.ReadAll(resp.Body)
+	c, _, err := image.DecodeConfig(bytes.NewReader(b))
+	if err != nil || c.Width != ThumbnailSize || c.Height != ThumbnailSize/2 || len(b) > MaxThumbnailBytes {
+		t.Fatalf("thumbnail %dx%d, %d bytes, %v", c.Width, c.Height, len(b), err)
+	}
+}
@@ -67,6 +67,14 @@ type VideoMessage struct {
 	Duration  uint   `json:"duration,omitempty"`
 }
 
+// FileMessage structure
+type FileMessage struct {
+	TextMessage
+	Media    string `json:"media"`
+	Size     uint   `json:"size"`
+	FileName string `json:"file_name"`
+}
+
 // MessageType for viber messaging
 type MessageType string
 
@@ -155,17 +163,29 @@ func (v *Viber) SendPictureMessage(receiver string, msg string, url string, thum
 	return v.SendMessage(receiver, v.NewPictureMessage(msg, url, thumbURL))
 }
 
-// SendPublicMessage from public account
+// SendPublicMessage from public account, min_api_version is raised to the version message features require
 func (v *Viber) SendPublicMessage(from string, m Message) (msgToken uint64, err error) {
 	// text, picture, video, file, location, contact, sticker and url
 	m.SetFrom(from)
-	return v.sendMessage("https://chatapi.viber.com/pa/post", m)
+	setMinAPIVersion(m)
+	if v.CheckMedia {
+		if err := v.Preflight(m); err != nil {
+			return 0, err
+		}
+	}
+	return v.sendMessage(v.apiURL("post"), m)
 }
 
-// SendMessage to receiver
+// SendMessage to receiver, min_api_version is raised to the version message features require
 func (v *Viber) SendMessage(to string, m Message) (msgToken uint64, err error) {
 	m.SetReceiver(to)
-	return v.sendMessage("https://chatapi.viber.com/pa/send_message", m)
+	setMinAPIVersion(m)
+	if v.CheckMedia {
+		if err := v.Preflight(m); err != nil {
+			return 0, err
+		}
+	}
+	return v.sendMessage(v.apiURL("send_message"), m)
 }
 
 // SetReceiver for text message
@@ -0,0 +1,229 @@
+package viber
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Metrics receives instrumentation of Viber app activity
+type Metrics interface {
+	// APICall is called after each Viber API call with endpoint name, Viber status and duration.
+	// Status is -1 if call failed or response can't be decoded.
+	APICall(endpoint string, status int, d time.Duration)
+
+	// Event is called for each received webhook event, duplicates excluded
+	Event(event EventType)
+
+	// SignatureFailure is called when webhook request signature doesn't match
+	SignatureFailure()
+
+	// Callback is called with duration of each event callback
+	Callback(event EventType, d time.Duration)
+
+	// QueueDepth reports number of callbacks waiting in Dispatcher queue, if Dispatcher has Len() int method
+	QueueDepth(n int)
+}
+
+// PrometheusMetrics collects Metrics in memory and serves them in Prometheus text exposition format
+type PrometheusMetrics struct {
+	mu                sync.Mutex
+	apiCalls          map[[2]string]uint64
+	apiDurations      map[string]*histogram
+	events            map[string]uint64
+	signatureFailures uint64
+	callbacks         map[string]*histogram
+	queueDepth        int
+}
+
+// NewPrometheusMetrics returns empty PrometheusMetrics
+func NewPrometheusMetrics() *PrometheusMetrics {
+	return &PrometheusMetrics{
+		apiCalls:     make(map[[2]string]uint64),
+		apiDurations: make(map[string]*histogram),
+		events:       make(map[string]uint64),
+		callbacks:    make(map[string]*histogram),
+	}
+}
+
+// APICall counts API call by endpoint and status and observes its duration
+func (p *PrometheusMetrics) APICall(endpoint string, status int, d time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.apiCalls[[2]string{endpoint, strconv.Itoa(status)}]++
+	observe(p.apiDurations, endpoint, d)
+}
+
+// Event counts received webhook event by type
+func (p *PrometheusMetrics) Event(event EventType) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.events[string(event)]++
+}
+
+// SignatureFailure counts rejected webhook request
+func (p *PrometheusMetrics) SignatureFailure() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.signatureFailures++
+}
+
+// Callback observes duration of event callback
+func (p *PrometheusMetrics) Callback(event EventType, d time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	observe(p.callbacks, string(event), d)
+}
+
+// QueueDepth sets current dispatcher queue depth
+func (p *PrometheusMetrics) QueueDepth(n int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.queueDepth = n
+}
+
+// ServeHTTP writes metrics in Prometheus text exposition format
+func (p *PrometheusMetrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	p.WriteTo(w)
+}
+
+// WriteTo writes metrics in Prometheus text exposition format to w
+func (p *PrometheusMetrics) WriteTo(w io.Writer) (int64, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	cw := &countWriter{w: w}
+
+	fmt.Fprintln(cw, "# HELP viber_api_calls_total Viber API calls by endpoint and Viber status.")
+	fmt.Fprintln(cw, "# TYPE viber_api_calls_total counter")
+	keys := make([][2]string, 0, len(p.apiCalls))
+	for k := range p.apiCalls {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i][0] != keys[j][0] {
+			return keys[i][0] < keys[j][0]
+		}
+		return keys[i][1] < keys[j][1]
+	})
+	for _, k := range keys {
+		fmt.Fprintf(cw, "viber_api_calls_total{endpoint=\"%s\",status=\"%s\"} %d\n", labelValue(k[0]), k[1], p.apiCalls[k])
+	}
+
+	writeHistograms(cw, "viber_api_call_duration_seconds", "Viber API call duration.", "endpoint", p.apiDurations)
+
+	fmt.Fprintln(cw, "# HELP viber_events_total Received webhook events by type.")
+	fmt.Fprintln(cw, "# TYPE viber_events_total counter")
+	for _, e := range sortedKeys(p.events) {
+		fmt.Fprintf(cw, "viber_events_total{event=\"%s\"} %d\n", labelValue(e), p.events[e])
+	}
+
+	fmt.Fprintln(cw, "# HELP viber_signature_failures_total Webhook requests with invalid signature.")
+	fmt.Fprintln(cw, "# TYPE viber_signature_failures_total counter")
+	fmt.Fprintf(cw, "viber_signature_failures_total %d\n", p.signatureFailures)
+
+	writeHistograms(cw, "viber_callback_duration_seconds", "Event callback duration.", "event", p.callbacks)
+
+	fmt.Fprintln(cw, "# HELP viber_dispatch_queue_depth Callbacks waiting in dispatcher queue.")
+	fmt.Fprintln(cw, "# TYPE viber_dispatch_queue_depth gauge")
+	fmt.Fprintf(cw, "viber_dispatch_queue_depth %d\n", p.queueDepth)
+
+	return cw.n, cw.err
+}
+
+// histogram buckets in seconds
+var histogramBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+type histogram struct {
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// observe d in histogram with label value
+func observe(m map[string]*histogram, label string, d time.Duration) {
+	h, ok := m[label]
+	if !ok {
+		h = &histogram{counts: make([]uint64, len(histogramBuckets))}
+		m[label] = h
+	}
+
+	s := d.Seconds()
+	for i, le := range histogramBuckets {
+		if s <= le {
+			h.counts[i]++
+		}
+	}
+	h.sum += s
+	h.count++
+}
+
+func writeHistograms(w io.Writer, name, help, label string, m map[string]*histogram) {
+	fmt.Fprintf(w, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(w, "# TYPE %s histogram\n", name)
+	labels := make([]string, 0, len(m))
+	for l := range m {
+		labels = append(labels, l)
+	}
+	sort.Strings(labels)
+	for _, l := range labels {
+		h := m[l]
+		lv := labelValue(l)
+		for i, le := range histogramBuckets {
+			fmt.Fprintf(w, "%s_bucket{%s=\"%s\",le=\"%s\"} %d\n", name, label, lv, strconv.FormatFloat(le, 'g', -1, 64), h.counts[i])
+		}
+		fmt.Fprintf(w, "%s_bucket{%s=\"%s\",le=\"+Inf\"} %d\n", name, label, lv, h.count)
+		fmt.Fprintf(w, "%s_sum{%s=\"%s\"} %g\n", name, label, lv, h.sum)
+		fmt.Fprintf(w, "%s_count{%s=\"%s\"} %d\n", name, label, lv, h.count)
+	}
+}
+
+// labelEscaper escapes label value as Prometheus text format requires, other characters are written as is
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func labelValue(s string) string {
+	return labelEscaper.Replace(s)
+}
+
+func sortedKeys(m map[string]uint64) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// countWriter counts written bytes and keeps the first error
+type countWriter struct {
+	w   io.Writer
+	n   int64
+	err error
+}
+
+func (cw *countWriter) Write(p []byte) (int, error) {
+	if cw.err != nil {
+		return 0, cw.err
+	}
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	cw.err = err
+	return n, err
+}
+
+// callback runs f and reports its duration to Metrics
+func (v *Viber) callback(event EventType, f func()) {
+	if v.Metrics == nil {
+		f()
+		return
+	}
+	start := time.Now()
+	f()
+	v.Metrics.Callback(event, time.Since(start))
+}
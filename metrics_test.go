@@ -0,0 +1,69 @@
+package viber
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPrometheusMetrics(t *testing.T) {
+	p := NewPrometheusMetrics()
+	// durations are binary fractions of second, so their sum is exact
+	for _, d := range []time.Duration{time.Second / 256, time.Second / 32, 3 * time.Second, 20 * time.Second} {
+		p.APICall("send_message", 0, d)
+	}
+	p.APICall("send_message", -1, time.Second/512)
+	p.Event(EventType("a\"b\\c\nd é"))
+	p.SignatureFailure()
+	p.QueueDepth(4)
+
+	var b strings.Builder
+	n, err := p.WriteTo(&b)
+	if err != nil || n != int64(b.Len()) {
+		t.Fatalf("written %d of %d bytes, err %v", n, b.Len(), err)
+	}
+	out := b.String()
+
+	// buckets are cumulative, observation above the last bucket is counted only in +Inf
+	for _, line := range []string{
+		`viber_api_calls_total{endpoint="send_message",status="-1"} 1`,
+		`viber_api_calls_total{endpoint="send_message",status="0"} 4`,
+		`viber_api_call_duration_seconds_bucket{endpoint="send_message",le="0.005"} 2`,
+		`viber_api_call_duration_seconds_bucket{endpoint="send_message",le="0.025"} 2`,
+		`viber_api_call_duration_seconds_bucket{endpoint="send_message",le="0.05"} 3`,
+		`viber_api_call_duration_seconds_bucket{endpoint="send_message",le="2.5"} 3`,
+		`viber_api_call_duration_seconds_bucket{endpoint="send_message",le="5"} 4`,
+		`viber_api_call_duration_seconds_bucket{endpoint="send_message",le="10"} 4`,
+		`viber_api_call_duration_seconds_bucket{endpoint="send_message",le="+Inf"} 5`,
+		`viber_api_call_duration_seconds_sum{endpoint="send_message"} 23.037109375`,
+		`viber_api_call_duration_seconds_count{endpoint="send_message"} 5`,
+		`viber_events_total{event="a\"b\\c\nd é"} 1`,
+		`viber_signature_failures_total 1`,
+		`viber_dispatch_queue_depth 4`,
+		`# TYPE viber_callback_duration_seconds histogram`,
+	} {
+		if !strings.Contains(out, line+"\n") {
+			t.Errorf("missing line %s", line)
+		}
+	}
+	if t.Failed() {
+		t.Log(out)
+	}
+}
+
+func TestMuxSignatureFailureMetrics(t *testing.T) {
+	p := NewPrometheusMetrics()
+	m := NewMux()
+	m.Metrics = p
+	m.Handle("/bot", New("key", "bot", ""))
+
+	if w := postWebhook(m, "other", `{"event":"webhook","timestamp":1}`); w.Code != http.StatusUnauthorized {
+		t.Fatalf("unrouted request status %d", w.Code)
+	}
+	var b strings.Builder
+	p.WriteTo(&b)
+	if !strings.Contains(b.String(), "viber_signature_failures_total 1\n") {
+		t.Fatalf("mux signature failure not counted:\n%s", b.String())
+	}
+}
@@ -0,0 +1,169 @@
+package viber
+
+import (
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Mux serves webhooks of many Viber bots from one server.
+// Requests are routed by URL path. If no path matches, the request is passed to the first bot
+// whose auth token verifies X-Viber-Content-Signature, so many bots can share one webhook URL.
+type Mux struct {
+	// Client for API calls, its Transport and connection pool are shared by all bots
+	Client *http.Client
+
+	// Dispatcher for event callbacks shared by all bots, nil runs each callback in new goroutine
+	Dispatcher Dispatcher
+
+	// MaxBodySize of webhook request routed by signature, 1MB if not set
+	MaxBodySize int64
+
+	// Logger for requests which can't be routed to a bot, global Log is used if not set
+	Logger *slog.Logger
+
+	// Metrics receives signature failures of requests which can't be routed to a bot, nil disables it
+	Metrics Metrics
+
+	mu    sync.RWMutex
+	paths map[string]*Viber
+	order []string
+}
+
+// NewMux returns Mux with shared http client
+func NewMux() *Mux {
+	return &Mux{
+		Client: newHTTPClient(),
+		paths:  make(map[string]*Viber),
+	}
+}
+
+// Handle registers bot v for webhook path.
+// Bot will use copy of Mux Client, unless its client is set by WithHTTPClient or WithTransport,
+// and Mux Dispatcher, if Dispatcher is not already set on the bot.
+func (m *Mux) Handle(path string, v *Viber) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.paths == nil {
+		m.paths = make(map[string]*Viber)
+	}
+	if _, ok := m.paths[path]; !ok {
+		m.order = append(m.order, path)
+	}
+	m.paths[path] = v
+
+	// bot gets its own copy of Mux client sharing its Transport, so request timeout
+	// can still be set per bot. Client set with WithHTTPClient or WithTransport is kept.
+	if m.Client != nil && (v.client == nil || !v.ownClient) {
+		c := *m.Client
+		if v.client != nil && v.client.Timeout != 0 {
+			c.Timeout = v.client.Timeout
+		}
+		v.client = &c
+	}
+	if v.Dispatcher == nil {
+		v.Dispatcher = m.Dispatcher
+	}
+}
+
+// Bot registered for path
+func (m *Mux) Bot(path string) (*Viber, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	v, ok := m.paths[path]
+	return v, ok
+}
+
+// ServeHTTP routes webhook request to the bot by path or by signature
+func (m *Mux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if v, ok := m.Bot(r.URL.Path); ok {
+		v.ServeHTTP(w, r)
+		return
+	}
+
+	body, err := readBody(r, m.MaxBodySize)
+	if err != nil {
+		m.logger().Warn("webhook request rejected", slog.String("error", err.Error()))
+		status := http.StatusBadRequest
+		if err == ErrBodyTooLarge {
+			status = http.StatusRequestEntityTooLarge
+		}
+		http.Error(w, http.StatusText(status), status)
+		return
+	}
+
+	signature := r.Header.Get("X-Viber-Content-Signature")
+	m.mu.RLock()
+	var bot *Viber
+	for _, path := range m.order {
+		if _, ok := m.paths[path].checkHMAC(body, signature); ok {
+			bot = m.paths[path]
+			break
+		}
+	}
+	m.mu.RUnlock()
+
+	if bot == nil {
+		if m.Metrics != nil {
+			m.Metrics.SignatureFailure()
+		}
+		m.logger().Warn("webhook request rejected", slog.String("error", ErrSignature.Error()))
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+	bot.serve(r.Context(), w, body, signature)
+}
+
+// SetWebhooks sets webhook of every bot to baseURL followed by the bot path.
+// eventTypes are passed to SetWebhook of each bot. Returned error is MuxError.
+func (m *Mux) SetWebhooks(baseURL string, eventTypes []EventType) error {
+	m.mu.RLock()
+	paths := append([]string(nil), m.order...)
+	bots := make([]*Viber, len(paths))
+	for i, path := range paths {
+		bots[i] = m.paths[path]
+	}
+	m.mu.RUnlock()
+
+	baseURL = strings.TrimSuffix(baseURL, "/")
+	errs := MuxError{}
+	for i, v := range bots {
+		if _, err := v.SetWebhook(baseURL+paths[i], eventTypes); err != nil {
+			errs[paths[i]] = err
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// MuxError holds errors of Mux bulk operations by bot path
+type MuxError map[string]error
+
+// Error interface function
+func (e MuxError) Error() string {
+	paths := make([]string, 0, len(e))
+	for path := range e {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	msgs := make([]string, len(paths))
+	for i, path := range paths {
+		msgs[i] = path + ": " + e[path].Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// logger of m, or fallback logger writing to global Log
+func (m *Mux) logger() *slog.Logger {
+	if m.Logger != nil {
+		return m.Logger
+	}
+	return fallbackLogger
+}
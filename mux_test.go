@@ -0,0 +1,34 @@
+package viber
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestMuxHandleClient(t *testing.T) {
+	m := NewMux()
+
+	a := New("a", "a", "")
+	b := New("b", "b", "", WithRequestTimeout(5*time.Second))
+	own := &http.Client{}
+	c := New("c", "c", "", WithHTTPClient(own))
+	m.Handle("/a", a)
+	m.Handle("/b", b)
+	m.Handle("/c", c)
+
+	if a.client == m.Client || a.client.Transport != m.Client.Transport {
+		t.Fatal("bot should get copy of Mux client sharing its transport")
+	}
+	if b.client.Timeout != 5*time.Second {
+		t.Fatalf("bot request timeout %v, want 5s", b.client.Timeout)
+	}
+	if c.client != own {
+		t.Fatal("client set with WithHTTPClient replaced by Mux client")
+	}
+
+	a.SetRequestTimeout(time.Second)
+	if m.Client.Timeout != 0 || b.client.Timeout != 5*time.Second {
+		t.Fatal("request timeout of one bot changed other clients")
+	}
+}
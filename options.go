@@ -0,0 +1,66 @@
+package viber
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DefaultBaseURL of Viber REST API
+const DefaultBaseURL = "https://chatapi.viber.com/pa/"
+
+// Option configures Viber app created with New
+type Option func(v *Viber)
+
+// WithBaseURL sets base URL of Viber REST API, for proxies or local stubs
+func WithBaseURL(baseURL string) Option {
+	return func(v *Viber) {
+		v.BaseURL = baseURL
+	}
+}
+
+// WithHTTPClient sets http client used for API calls
+func WithHTTPClient(c *http.Client) Option {
+	return func(v *Viber) {
+		v.client = c
+		v.ownClient = true
+	}
+}
+
+// WithTransport sets RoundTripper of http client used for API calls.
+// Client set with WithHTTPClient is copied, so the caller's client is not modified.
+func WithTransport(rt http.RoundTripper) Option {
+	return func(v *Viber) {
+		c := http.Client{}
+		if v.client != nil {
+			c = *v.client
+		}
+		c.Transport = rt
+		v.client = &c
+		v.ownClient = true
+	}
+}
+
+// WithMediaTransport sets RoundTripper used to download and check media on other hosts.
+// Media is fetched with default transport unless set, so transport set with WithTransport is used for media only if passed here too.
+func WithMediaTransport(rt http.RoundTripper) Option {
+	return func(v *Viber) {
+		v.mediaClient = &http.Client{Transport: rt}
+	}
+}
+
+// WithRequestTimeout sets timeout for API calls
+func WithRequestTimeout(t time.Duration) Option {
+	return func(v *Viber) {
+		v.SetRequestTimeout(t)
+	}
+}
+
+// apiURL of Viber REST API endpoint
+func (v *Viber) apiURL(endpoint string) string {
+	base := v.BaseURL
+	if base == "" {
+		base = DefaultBaseURL
+	}
+	return strings.TrimSuffix(base, "/") + "/" + endpoint
+}
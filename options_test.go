@@ -0,0 +1,48 @@
+package viber
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestWithTransportCopiesClient(t *testing.T) {
+	shared := &http.Client{}
+	rt := &http.Transport{}
+	v := New("key", "bot", "", WithHTTPClient(shared), WithTransport(rt))
+
+	if shared.Transport != nil {
+		t.Fatal("WithTransport modified client passed to WithHTTPClient")
+	}
+	if v.client == shared || v.client.Transport != rt {
+		t.Fatal("bot client should be copy with transport set")
+	}
+}
+
+func TestWithRequestTimeoutCopiesClient(t *testing.T) {
+	shared := &http.Client{}
+	v := New("key", "bot", "", WithHTTPClient(shared), WithRequestTimeout(3*time.Second))
+
+	if shared.Timeout != 0 {
+		t.Fatal("WithRequestTimeout modified client passed to WithHTTPClient")
+	}
+	if v.client == shared || v.client.Timeout != 3*time.Second {
+		t.Fatal("bot client should be copy with timeout set")
+	}
+
+	v.SetRequestTimeout(time.Second)
+	if shared.Timeout != 0 || v.client.Timeout != time.Second {
+		t.Fatal("SetRequestTimeout should set timeout of bot's own copy")
+	}
+}
+
+func TestAPIURL(t *testing.T) {
+	v := New("key", "bot", "")
+	if u := v.apiURL("send_message"); u != DefaultBaseURL+"send_message" {
+		t.Fatalf("default api url %s", u)
+	}
+	v = New("key", "bot", "", WithBaseURL("http://localhost:8080/pa"))
+	if u := v.apiURL("send_message"); u != "http://localhost:8080/pa/send_message" {
+		t.Fatalf("api url %s", u)
+	}
+}
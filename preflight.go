@@ -0,0 +1,152 @@
+package viber
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// Media limits of Viber messages
+const (
+	MaxPictureSize  = 1 << 20
+	MaxFileSize     = 50 << 20
+	MaxFileNameSize = 256
+)
+
+// MediaError is returned when message media doesn't meet Viber requirements
+type MediaError struct {
+	Type   MessageType
+	Media  string
+	Reason string
+}
+
+func (e *MediaError) Error() string {
+	return fmt.Sprintf("viber: %s %s: %s", e.Type, e.Media, e.Reason)
+}
+
+// mediaRule of message type, media must match either content type or extension.
+// Extensions in forbidden are rejected regardless of content type.
+type mediaRule struct {
+	typ       MessageType
+	maxSize   int64
+	types     map[string]bool
+	exts      map[string]bool
+	forbidden map[string]bool
+	formats   string
+}
+
+var (
+	pictureRule = mediaRule{
+		typ:     TypePictureMessage,
+		maxSize: MaxPictureSize,
+		types:   map[string]bool{"image/jpeg": true, "image/png": true, "image/gif": true},
+		exts:    map[string]bool{".jpg": true, ".jpeg": true, ".png": true, ".gif": true},
+		formats: "JPEG, PNG and GIF",
+	}
+	videoRule = mediaRule{
+		typ:     TypeVideoMessage,
+		maxSize: MaxVideoSize,
+		types:   map[string]bool{"video/mp4": true},
+		exts:    map[string]bool{".mp4": true, ".m4v": true},
+		formats: "MP4",
+	}
+	fileRule = mediaRule{
+		typ:     TypeFileMessage,
+		maxSize: MaxFileSize,
+		forbidden: map[string]bool{
+			".apk": true, ".bat": true, ".bin": true, ".cmd": true, ".com": true, ".cpl": true,
+			".dll": true, ".exe": true, ".gadget": true, ".hta": true, ".ins": true, ".isp": true,
+			".jar": true, ".jse": true, ".lib": true, ".lnk": true, ".msc": true, ".msi": true,
+			".msp": true, ".mst": true, ".pif": true, ".scr": true, ".sct": true, ".shb": true,
+			".sys": true, ".vb": true, ".vbe": true, ".vbs": true, ".vxd": true, ".wsc": true,
+			".wsf": true, ".wsh": true,
+		},
+	}
+)
+
+// check probed media info against the rule
+func (r mediaRule) check(source string, info mediaInfo) error {
+	if r.forbidden[info.ext] {
+		return &MediaError{Type: r.typ, Media: source, Reason: fmt.Sprintf("forbidden file extension %s", info.ext)}
+	}
+	if r.types != nil && !r.types[info.contentType] && !r.exts[info.ext] {
+		return &MediaError{Type: r.typ, Media: source, Reason: fmt.Sprintf("unsupported format %q, supported are %s", info.contentType, r.formats)}
+	}
+	return r.checkSize(source, info.size)
+}
+
+// checkSize of media, negative size is unknown and isn't checked
+func (r mediaRule) checkSize(source string, size int64) error {
+	if size > r.maxSize {
+		return &MediaError{Type: r.typ, Media: source, Reason: fmt.Sprintf("size %d bytes exceeds %d bytes", size, r.maxSize)}
+	}
+	return nil
+}
+
+// checkURL of media, Viber accepts only http(s) URLs
+func (r mediaRule) checkURL(media string) error {
+	u, err := url.Parse(media)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return &MediaError{Type: r.typ, Media: media, Reason: "media must be http or https URL"}
+	}
+	return nil
+}
+
+// Preflight checks media of picture, video and file message m against Viber requirements:
+// URL scheme, reachability, content type, extension and size. Media is checked with HEAD request.
+// Returns *MediaError if media would be rejected, other message types are not checked.
+// Set CheckMedia to run Preflight before each SendMessage, SendPublicMessage, Broadcast,
+// Queue.Enqueue and Scheduler job.
+func (v *Viber) Preflight(m Message) error {
+	switch m := m.(type) {
+	case *PictureMessage:
+		if m.Thumbnail != "" {
+			if err := pictureRule.checkURL(m.Thumbnail); err != nil {
+				return err
+			}
+		}
+		return v.preflight(pictureRule, m.Media)
+
+	case *VideoMessage:
+		if m.Size == 0 {
+			return &MediaError{Type: TypeVideoMessage, Media: m.Media, Reason: "size is required"}
+		}
+		if err := videoRule.checkSize(m.Media, int64(m.Size)); err != nil {
+			return err
+		}
+		if m.Thumbnail != "" {
+			if err := videoRule.checkURL(m.Thumbnail); err != nil {
+				return err
+			}
+		}
+		return v.preflight(videoRule, m.Media)
+
+	case *FileMessage:
+		if m.Size == 0 {
+			return &MediaError{Type: TypeFileMessage, Media: m.Media, Reason: "size is required"}
+		}
+		if m.FileName == "" || len(m.FileName) > MaxFileNameSize {
+			return &MediaError{Type: TypeFileMessage, Media: m.Media, Reason: fmt.Sprintf("file name must have 1 to %d characters", MaxFileNameSize)}
+		}
+		if ext := strings.ToLower(path.Ext(m.FileName)); fileRule.forbidden[ext] {
+			return &MediaError{Type: TypeFileMessage, Media: m.Media, Reason: fmt.Sprintf("forbidden file extension %s", ext)}
+		}
+		if err := fileRule.checkSize(m.Media, int64(m.Size)); err != nil {
+			return err
+		}
+		return v.preflight(fileRule, m.Media)
+	}
+	return nil
+}
+
+func (v *Viber) preflight(r mediaRule, media string) error {
+	if err := r.checkURL(media); err != nil {
+		return err
+	}
+	info, err := v.probeURL(media)
+	if err != nil {
+		return &MediaError{Type: r.typ, Media: media, Reason: err.Error()}
+	}
+	return r.check(media, info)
+}
@@ -0,0 +1,90 @@
+package viber
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+// mediaStub serves HEAD responses with content type and length by path
+func mediaStub() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/small.jpg":
+			w.Header().Set("Content-Type", "image/jpeg")
+			w.Header().Set("Content-Length", "1000")
+		case "/large.jpg":
+			w.Header().Set("Content-Type", "image/jpeg")
+			w.Header().Set("Content-Length", "3000000")
+		case "/doc.bmp":
+			w.Header().Set("Content-Type", "image/bmp")
+			w.Header().Set("Content-Length", "1000")
+		case "/clip.mp4":
+			w.Header().Set("Content-Type", "video/mp4")
+			w.Header().Set("Content-Length", "1000")
+		case "/large.mp4":
+			w.Header().Set("Content-Type", "video/mp4")
+			w.Header().Set("Content-Length", "27262977")
+		case "/clip.avi":
+			w.Header().Set("Content-Type", "video/x-msvideo")
+			w.Header().Set("Content-Length", "1000")
+		case "/stream.mp4":
+			// headers are sent before length is known
+			w.Header().Set("Content-Type", "video/mp4")
+			w.(http.Flusher).Flush()
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+func TestPreflight(t *testing.T) {
+	srv := mediaStub()
+	defer srv.Close()
+	v := New("key", "bot", "")
+
+	tests := []struct {
+		m  Message
+		ok bool
+	}{
+		{v.NewPictureMessage("", srv.URL+"/small.jpg", ""), true},
+		{v.NewPictureMessage("", srv.URL+"/large.jpg", ""), false},
+		{v.NewPictureMessage("", srv.URL+"/doc.bmp", ""), false},
+		{v.NewPictureMessage("", srv.URL+"/missing.jpg", ""), false},
+		{v.NewPictureMessage("", "ftp://example.com/a.jpg", ""), false},
+		{v.NewVideoMessage(srv.URL+"/v.mp4", "", 0, 0), false},
+		{v.NewFileMessage(srv.URL+"/small.jpg", "setup.exe", 1000), false},
+		{v.NewTextMessage("text is not checked"), true},
+	}
+	for i, tt := range tests {
+		err := v.Preflight(tt.m)
+		var me *MediaError
+		if tt.ok && err != nil || !tt.ok && !errors.As(err, &me) {
+			t.Errorf("%d: unexpected result %v", i, err)
+		}
+	}
+}
+
+func TestPreflightQueueAndScheduler(t *testing.T) {
+	srv := mediaStub()
+	defer srv.Close()
+	v := New("key", "bot", "")
+	v.CheckMedia = true
+	m := v.NewPictureMessage("", srv.URL+"/large.jpg", "")
+
+	qs, _ := OpenFileQueueStore(filepath.Join(t.TempDir(), "queue.log"))
+	defer qs.Close()
+	q, _ := v.NewQueue(qs)
+	if _, err := q.Enqueue("user", m); err == nil {
+		t.Fatal("Enqueue accepted picture over the size limit")
+	}
+
+	js, _ := OpenFileJobStore(filepath.Join(t.TempDir(), "jobs.log"))
+	defer js.Close()
+	s, _ := v.NewScheduler(js)
+	if _, err := s.SendEvery("0 9 * * *", nil, "user", m); err == nil {
+		t.Fatal("SendEvery accepted picture over the size limit")
+	}
+}
@@ -0,0 +1,188 @@
+package viber
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+)
+
+// OnlineChanged is sent to PresenceWatcher subscribers when online status of watched user changes
+type OnlineChanged struct {
+	User     UserOnline
+	Previous OnlineStatus
+
+	// Initial is set for the first status of newly watched user, Previous is unknown then
+	Initial bool
+}
+
+// PresenceWatcher polls online status of watched users with UserOnline and notifies subscribers about changes.
+// TryLater status is ignored, so users keep their last known status.
+type PresenceWatcher struct {
+	// Interval between polls, one minute if not set
+	Interval time.Duration
+
+	// Limiter between get_online calls of one poll, nil doesn't limit
+	Limiter Limiter
+
+	v *Viber
+
+	mu      sync.Mutex
+	watched map[string]bool
+	status  map[string]UserOnline
+	subs    map[chan OnlineChanged]bool
+
+	runner runner
+}
+
+// defaultPresenceInterval between PresenceWatcher polls
+const defaultPresenceInterval = time.Minute
+
+// NewPresenceWatcher returns presence watcher of v. Call Start to start polling.
+func (v *Viber) NewPresenceWatcher() *PresenceWatcher {
+	return &PresenceWatcher{
+		v:       v,
+		watched: make(map[string]bool),
+		status:  make(map[string]UserOnline),
+		subs:    make(map[chan OnlineChanged]bool),
+	}
+}
+
+// Watch users with ids
+func (w *PresenceWatcher) Watch(ids ...string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, id := range ids {
+		w.watched[id] = true
+	}
+}
+
+// Unwatch users with ids
+func (w *PresenceWatcher) Unwatch(ids ...string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, id := range ids {
+		delete(w.watched, id)
+		delete(w.status, id)
+	}
+}
+
+// Status returns last known status of watched user
+func (w *PresenceWatcher) Status(id string) (UserOnline, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	u, ok := w.status[id]
+	return u, ok
+}
+
+// Snapshot returns last known statuses of watched users
+func (w *PresenceWatcher) Snapshot() map[string]UserOnline {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	snap := make(map[string]UserOnline, len(w.status))
+	for id, u := range w.status {
+		snap[id] = u
+	}
+	return snap
+}
+
+// Subscribe returns channel of status changes with buffer size and function which cancels subscription
+// and closes the channel. Changes are dropped for subscribers whose buffer is full.
+func (w *PresenceWatcher) Subscribe(buffer int) (<-chan OnlineChanged, func()) {
+	ch := make(chan OnlineChanged, buffer)
+	w.mu.Lock()
+	w.subs[ch] = true
+	w.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			w.mu.Lock()
+			delete(w.subs, ch)
+			w.mu.Unlock()
+			close(ch)
+		})
+	}
+}
+
+// Poll online status of watched users once, in batches of MaxOnlineIDs
+func (w *PresenceWatcher) Poll(ctx context.Context) error {
+	w.mu.Lock()
+	ids := make([]string, 0, len(w.watched))
+	for id := range w.watched {
+		ids = append(ids, id)
+	}
+	w.mu.Unlock()
+	sort.Strings(ids)
+
+	v := w.v.WithContext(ctx)
+	for i := 0; i < len(ids); i += MaxOnlineIDs {
+		if w.Limiter != nil {
+			if err := w.Limiter.Wait(ctx); err != nil {
+				return err
+			}
+		}
+		users, err := v.userOnline(ids[i:min(i+MaxOnlineIDs, len(ids))])
+		if err != nil {
+			return err
+		}
+		w.update(users)
+	}
+	return nil
+}
+
+// update statuses and notify subscribers about changes
+func (w *PresenceWatcher) update(users []UserOnline) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for _, u := range users {
+		if !w.watched[u.ID] || u.OnlineStatus == TryLater {
+			continue
+		}
+		prev, known := w.status[u.ID]
+		w.status[u.ID] = u
+		if known && prev.OnlineStatus == u.OnlineStatus {
+			continue
+		}
+
+		c := OnlineChanged{User: u, Previous: prev.OnlineStatus, Initial: !known}
+		for ch := range w.subs {
+			select {
+			case ch <- c:
+			default:
+			}
+		}
+	}
+}
+
+// Start polling in background
+func (w *PresenceWatcher) Start() {
+	w.runner.start(w.run)
+}
+
+// Stop polling and wait for poll in progress to finish
+func (w *PresenceWatcher) Stop() {
+	w.runner.stop()
+}
+
+func (w *PresenceWatcher) run(ctx context.Context) {
+	interval := w.Interval
+	if interval <= 0 {
+		interval = defaultPresenceInterval
+	}
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		if err := w.Poll(ctx); err != nil && ctx.Err() == nil {
+			w.v.logger().Error("presence poll failed", slog.String("error", err.Error()))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
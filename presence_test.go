@@ -0,0 +1,81 @@
+package viber
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+// onlineStub answers get_online with status returned by status func for each id
+func onlineStub(status func(id string) OnlineStatus) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			IDs []string `json:"ids"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		if len(req.IDs) > MaxOnlineIDs {
+			http.Error(w, "too many ids", http.StatusBadRequest)
+			return
+		}
+		users := make([]map[string]interface{}, 0, len(req.IDs))
+		// reversed, so callers can't rely on response order
+		for i := len(req.IDs) - 1; i >= 0; i-- {
+			users = append(users, map[string]interface{}{"id": req.IDs[i], "online_status": status(req.IDs[i])})
+		}
+		b, _ := json.Marshal(users)
+		fmt.Fprintf(w, `{"status":0,"status_message":"ok","users":%s}`, b)
+	}))
+}
+
+func TestPresenceWatcher(t *testing.T) {
+	var online atomic.Value
+	online.Store("")
+	srv := onlineStub(func(id string) OnlineStatus {
+		if id == online.Load().(string) {
+			return Online
+		}
+		return Offline
+	})
+	defer srv.Close()
+
+	v := New("key", "bot", "", WithBaseURL(srv.URL))
+	w := v.NewPresenceWatcher()
+	for i := 0; i < 150; i++ {
+		w.Watch(fmt.Sprint("u", i))
+	}
+	changes, unsubscribe := w.Subscribe(200)
+
+	if err := w.Poll(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(changes); n != 150 {
+		t.Fatalf("%d initial changes, want 150", n)
+	}
+	for len(changes) > 0 {
+		if c := <-changes; !c.Initial {
+			t.Fatalf("first status not initial: %+v", c)
+		}
+	}
+
+	online.Store("u120")
+	w.Poll(context.Background())
+	if len(changes) != 1 {
+		t.Fatalf("%d changes, want 1", len(changes))
+	}
+	c := <-changes
+	if c.User.ID != "u120" || c.Previous != Offline || c.User.OnlineStatus != Online || c.Initial {
+		t.Fatalf("unexpected change %+v", c)
+	}
+	if u, _ := w.Status("u120"); u.OnlineStatus != Online {
+		t.Fatal("snapshot not updated")
+	}
+
+	unsubscribe()
+	if _, ok := <-changes; ok {
+		t.Fatal("channel not closed by unsubscribe")
+	}
+}
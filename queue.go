@@ -0,0 +1,333 @@
+package viber
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// QueueStatus of queued message
+type QueueStatus string
+
+// QueueStatus values
+const (
+	QueuePending = QueueStatus("pending")
+	QueueSent    = QueueStatus("sent")
+	QueueFailed  = QueueStatus("failed")
+)
+
+// QueueItem is message in the Queue with its delivery state
+type QueueItem struct {
+	ID       string          `json:"id"`
+	Receiver string          `json:"receiver"`
+	Message  json.RawMessage `json:"message"`
+	Status   QueueStatus     `json:"status"`
+	Attempts int             `json:"attempts"`
+	Token    uint64          `json:"token,omitempty"`
+	Error    string          `json:"error,omitempty"`
+	Created  time.Time       `json:"created"`
+	Updated  time.Time       `json:"updated"`
+	Next     time.Time       `json:"next,omitempty"`
+}
+
+// QueueStore persists queue items
+type QueueStore interface {
+	// Save item state
+	Save(item QueueItem) error
+	// Delete item
+	Delete(id string) error
+	// Load all saved items
+	Load() ([]QueueItem, error)
+}
+
+// FileQueueStore is QueueStore backed by local append-only file, compacted when opened and as it grows
+type FileQueueStore struct {
+	*fileStore[QueueItem]
+}
+
+// OpenFileQueueStore opens or creates queue file at path
+func OpenFileQueueStore(path string) (*FileQueueStore, error) {
+	s, err := openFileStore(path, func(item QueueItem) string { return item.ID })
+	if err != nil {
+		return nil, err
+	}
+	return &FileQueueStore{s}, nil
+}
+
+// ErrQueueItemNotFound is returned for unknown queue item id
+var ErrQueueItemNotFound = errors.New("viber: queue item not found")
+
+// Queue sends messages in background, persisting them in QueueStore before sending
+// so pending messages are sent after restart. Message which was sent right before a crash,
+// but not yet marked as sent, is sent again on restart.
+type Queue struct {
+	// Limiter for sending rate, nil sends without limit
+	Limiter Limiter
+
+	// MaxAttempts for sending a message, failed network calls and throttled calls are retried
+	MaxAttempts int
+
+	// RetryDelay before the first retry, doubled for each next attempt
+	RetryDelay time.Duration
+
+	// Done is called when message is sent or has failed permanently
+	Done func(item QueueItem)
+
+	v     *Viber
+	store QueueStore
+
+	mu    sync.Mutex
+	items map[string]*QueueItem
+	order []string // pending item ids in order of enqueuing
+	wake  chan struct{}
+
+	runner runner
+}
+
+// NewQueue returns queue of v which loads pending items from store. Call Start to start sending.
+func (v *Viber) NewQueue(store QueueStore) (*Queue, error) {
+	items, err := store.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	q := &Queue{
+		MaxAttempts: 5,
+		RetryDelay:  time.Second,
+		v:           v,
+		store:       store,
+		items:       make(map[string]*QueueItem),
+		wake:        make(chan struct{}, 1),
+	}
+	for i := range items {
+		item := items[i]
+		q.items[item.ID] = &item
+		if item.Status == QueuePending {
+			q.order = append(q.order, item.ID)
+		}
+	}
+	return q, nil
+}
+
+// Enqueue message m for receiver, returns queue item id.
+// Message is persisted before Enqueue returns. With CheckMedia set, media is checked by Preflight first.
+func (q *Queue) Enqueue(receiver string, m Message) (string, error) {
+	if q.v.CheckMedia {
+		if err := q.v.Preflight(m); err != nil {
+			return "", err
+		}
+	}
+	m.SetReceiver(receiver)
+	setMinAPIVersion(m)
+	b, err := json.Marshal(m)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	item := QueueItem{
+		ID:       newID(),
+		Receiver: receiver,
+		Message:  b,
+		Status:   QueuePending,
+		Created:  now,
+		Updated:  now,
+	}
+	if err := q.store.Save(item); err != nil {
+		return "", err
+	}
+
+	q.mu.Lock()
+	q.items[item.ID] = &item
+	q.order = append(q.order, item.ID)
+	q.mu.Unlock()
+
+	q.signal()
+	return item.ID, nil
+}
+
+// Item returns current state of queue item
+func (q *Queue) Item(id string) (QueueItem, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	item, ok := q.items[id]
+	if !ok {
+		return QueueItem{}, false
+	}
+	return *item, true
+}
+
+// Pending returns number of messages waiting to be sent
+func (q *Queue) Pending() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.order)
+}
+
+// Remove item from the queue, pending message won't be sent
+func (q *Queue) Remove(id string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if _, ok := q.items[id]; !ok {
+		return ErrQueueItemNotFound
+	}
+	if err := q.store.Delete(id); err != nil {
+		return err
+	}
+	delete(q.items, id)
+	q.removePending(id)
+	return nil
+}
+
+// Purge removes sent and failed items last updated before t
+func (q *Queue) Purge(t time.Time) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for id, item := range q.items {
+		if item.Status != QueuePending && item.Updated.Before(t) {
+			if err := q.store.Delete(id); err != nil {
+				return err
+			}
+			delete(q.items, id)
+		}
+	}
+	return nil
+}
+
+// Start sending queued messages in background
+func (q *Queue) Start() {
+	q.runner.start(q.run)
+}
+
+// Stop sending and wait for message being sent to finish
+func (q *Queue) Stop() {
+	q.runner.stop()
+}
+
+func (q *Queue) run(ctx context.Context) {
+	for {
+		item, wait := q.next()
+		if item == nil {
+			q.sleep(ctx, wait)
+			if ctx.Err() != nil {
+				return
+			}
+			continue
+		}
+
+		if q.Limiter != nil {
+			if err := q.Limiter.Wait(ctx); err != nil {
+				return
+			}
+		}
+
+		token, err := q.v.sendMessage(q.v.apiURL("send_message"), item.Message)
+		q.update(item, token, err)
+	}
+}
+
+// sleep until new item is queued, ctx is done or for d if d > 0
+func (q *Queue) sleep(ctx context.Context, d time.Duration) {
+	var timer <-chan time.Time
+	if d > 0 {
+		t := time.NewTimer(d)
+		defer t.Stop()
+		timer = t.C
+	}
+	select {
+	case <-ctx.Done():
+	case <-q.wake:
+	case <-timer:
+	}
+}
+
+// next returns copy of the first pending item which is due, or time to wait for the earliest retry
+func (q *Queue) next() (*QueueItem, time.Duration) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := time.Now()
+	var wait time.Duration
+	for _, id := range q.order {
+		item := q.items[id]
+		if !item.Next.After(now) {
+			c := *item
+			return &c, 0
+		}
+		if d := item.Next.Sub(now); wait == 0 || d < wait {
+			wait = d
+		}
+	}
+	return nil, wait
+}
+
+// update item state after sending attempt
+func (q *Queue) update(sent *QueueItem, token uint64, err error) {
+	q.mu.Lock()
+	item, ok := q.items[sent.ID]
+	if !ok {
+		// removed while sending
+		q.mu.Unlock()
+		return
+	}
+
+	now := time.Now()
+	item.Attempts++
+	item.Updated = now
+	switch {
+	case err == nil:
+		item.Status = QueueSent
+		item.Token = token
+		item.Error = ""
+	case temporary(err) && item.Attempts < q.MaxAttempts:
+		item.Error = err.Error()
+		item.Next = now.Add(q.RetryDelay << uint(item.Attempts-1))
+	default:
+		item.Status = QueueFailed
+		item.Error = err.Error()
+	}
+	if item.Status != QueuePending {
+		q.removePending(item.ID)
+	}
+	c := *item
+	if err := q.store.Save(c); err != nil {
+		q.v.logger().Error("queue item not saved", slog.String("id", c.ID), slog.String("error", err.Error()))
+	}
+	q.mu.Unlock()
+
+	if c.Status != QueuePending && q.Done != nil {
+		q.Done(c)
+	}
+}
+
+// removePending id from order, q.mu must be held
+func (q *Queue) removePending(id string) {
+	for i, pid := range q.order {
+		if pid == id {
+			q.order = append(q.order[:i], q.order[i+1:]...)
+			return
+		}
+	}
+}
+
+// signal run loop that new item is queued
+func (q *Queue) signal() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// newID returns random hex id
+func newID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,65 @@
+package viber
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestQueueRetryAndPersistence(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			fmt.Fprint(w, `{"status":12,"status_message":"tooManyRequests"}`)
+			return
+		}
+		fmt.Fprint(w, `{"status":0,"status_message":"ok","message_token":42}`)
+	}))
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "queue.log")
+	store, err := OpenFileQueueStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := New("key", "bot", "", WithBaseURL(srv.URL))
+	q, err := v.NewQueue(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q.RetryDelay = 10 * time.Millisecond
+	done := make(chan QueueItem, 1)
+	q.Done = func(item QueueItem) { done <- item }
+
+	id, err := q.Enqueue("user", v.NewTextMessage("hi"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	q.Start()
+	defer q.Stop()
+
+	select {
+	case item := <-done:
+		if item.ID != id || item.Status != QueueSent || item.Attempts != 2 || item.Token != 42 {
+			t.Fatalf("unexpected item %+v", item)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("message not sent")
+	}
+	q.Stop()
+	store.Close()
+
+	store, err = OpenFileQueueStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	items, _ := store.Load()
+	if len(items) != 1 || items[0].Status != QueueSent {
+		t.Fatalf("reopened store items %+v", items)
+	}
+}
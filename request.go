@@ -2,38 +2,187 @@ package viber
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
-	"io/ioutil"
+	"io"
+	"log/slog"
 	"net/http"
+	"path"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
+// bufPool of buffers for encoding API requests
+var bufPool = sync.Pool{
+	New: func() interface{} { return new(bytes.Buffer) },
+}
+
+// pooledBody is request body which returns its buffer to bufPool once the transport has closed it
+// and PostDataContext is done with the response. Transport may close the body from another goroutine
+// while it is still read, so reads are serialized with Close and body can't be read after Close.
+type pooledBody struct {
+	mu     sync.Mutex
+	r      *bytes.Reader
+	buf    *bytes.Buffer
+	closed bool
+	refs   atomic.Int32
+}
+
+func newPooledBody(buf *bytes.Buffer) *pooledBody {
+	b := &pooledBody{r: bytes.NewReader(buf.Bytes()), buf: buf}
+	b.refs.Store(2)
+	return b
+}
+
+func (b *pooledBody) Read(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return 0, http.ErrBodyReadAfterClose
+	}
+	return b.r.Read(p)
+}
+
+// Close releases transport's reference to the buffer
+func (b *pooledBody) Close() error {
+	b.mu.Lock()
+	closed := b.closed
+	b.closed = true
+	b.mu.Unlock()
+	if !closed {
+		b.release()
+	}
+	return nil
+}
+
+// release reference to the buffer, the last one returns it to the pool
+func (b *pooledBody) release() {
+	if b.refs.Add(-1) == 0 {
+		bufPool.Put(b.buf)
+	}
+}
+
+// newHTTPClient for API calls which keeps idle connections to Viber API open,
+// since default transport keeps only 2 idle connections per host
+func newHTTPClient() *http.Client {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.MaxIdleConns = 100
+	t.MaxIdleConnsPerHost = 100
+	return &http.Client{Transport: t}
+}
+
+// defaultClient for Viber apps created without New and without client option
+var defaultClient = newHTTPClient()
+
+// httpClient used for API calls, shared defaultClient if client is not set,
+// so copies made by WithContext don't create their own clients
+func (v *Viber) httpClient() *http.Client {
+	if v.client != nil {
+		return v.client
+	}
+	return defaultClient
+}
+
 // PostData to viber API
 func (v *Viber) PostData(url string, i interface{}) ([]byte, error) {
-	b, err := json.Marshal(i)
-	if err != nil {
+	return v.PostDataContext(v.Context(), url, i)
+}
+
+// PostDataContext to viber API with context
+func (v *Viber) PostDataContext(ctx context.Context, url string, i interface{}) ([]byte, error) {
+	buf := bufPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	if err := json.NewEncoder(buf).Encode(i); err != nil {
+		bufPool.Put(buf)
 		return nil, err
 	}
+	b := buf.Bytes()
 
-	Log.Println("Post data:", string(b))
+	endpoint := path.Base(url)
+	log := v.logger().With(slog.String("endpoint", endpoint))
+	v.logBody("post data", b)
 
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(b))
-	req.Header.Add("X-Viber-Auth-Token", v.AppKey)
-	req.Close = true
-	
-	if v.client == nil {
-		v.client = &http.Client{}
+	ctx, span := v.startSpan(ctx, "viber.api")
+	defer span.End()
+	span.SetAttribute("endpoint", endpoint)
+	if v.Tracer != nil {
+		if msgType := peakMessageType(b); msgType != "" {
+			span.SetAttribute("message_type", msgType)
+		}
+		if receiver := peekReceiver(b); receiver != "" {
+			span.SetAttribute("user_id", v.redactUser(receiver))
+		}
 	}
 
-	resp, err := v.client.Do(req)
+	req, err := http.NewRequestWithContext(ctx, "POST", url, nil)
+	if err != nil {
+		bufPool.Put(buf)
+		span.RecordError(err)
+		return nil, err
+	}
+	// buffer is returned to the pool when transport closes request body and response is read
+	body := newPooledBody(buf)
+	defer body.release()
+	req.Body = body
+	req.ContentLength = int64(len(b))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Add("X-Viber-Auth-Token", v.AppKey)
+
+	start := time.Now()
+	resp, err := v.httpClient().Do(req)
 	if err != nil {
+		v.apiCall(url, -1, start)
+		span.RecordError(err)
+		log.Error("api call failed", slog.String("error", err.Error()), slog.Duration("latency", time.Since(start)))
 		return nil, err
 	}
 
+	// body is read to EOF so connection can be reused
 	defer resp.Body.Close()
-	body, err := ioutil.ReadAll(resp.Body)
+	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
+		v.apiCall(url, -1, start)
+		span.RecordError(err)
+		log.Error("api call failed", slog.String("error", err.Error()), slog.Duration("latency", time.Since(start)))
 		return nil, err
 	}
 
-	return body, nil
+	status, token := peekResponse(respBody)
+	v.apiCall(url, status, start)
+	span.SetAttribute("status", status)
+	if token != 0 {
+		span.SetAttribute("message_token", token)
+	}
+	log.Info("api call", slog.Int("http_status", resp.StatusCode), slog.Int("status", status), slog.Duration("latency", time.Since(start)))
+	v.logBody("api response", respBody)
+	return respBody, nil
+}
+
+// peekResponse returns Viber status and message token from API response, status is -1 if response can't be decoded
+func peekResponse(b []byte) (status int, token uint64) {
+	r := struct {
+		Status       *int   `json:"status"`
+		MessageToken uint64 `json:"message_token"`
+	}{}
+	if err := json.Unmarshal(b, &r); err != nil || r.Status == nil {
+		return -1, 0
+	}
+	return *r.Status, r.MessageToken
+}
+
+// peekReceiver returns receiver of message posted to API
+func peekReceiver(b []byte) string {
+	r := struct {
+		Receiver string `json:"receiver"`
+	}{}
+	json.Unmarshal(b, &r)
+	return r.Receiver
+}
+
+// apiCall reports API call started at start to Metrics
+func (v *Viber) apiCall(url string, status int, start time.Time) {
+	if v.Metrics != nil {
+		v.Metrics.APICall(path.Base(url), status, time.Since(start))
+	}
 }
@@ -0,0 +1,127 @@
+package viber
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// apiStub answers every API call with successful send_message response
+func apiStub() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"status":0,"status_message":"ok","message_token":5741311803571721087}`)
+	}))
+}
+
+func TestPostData(t *testing.T) {
+	var token string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token = r.Header.Get("X-Viber-Auth-Token")
+		fmt.Fprint(w, `{"status":0,"status_message":"ok","message_token":1}`)
+	}))
+	defer srv.Close()
+
+	v := New("key", "bot", "", WithBaseURL(srv.URL))
+	msgToken, err := v.SendTextMessage("user", "hi")
+	if err != nil || msgToken != 1 {
+		t.Fatalf("token %d, err %v", msgToken, err)
+	}
+	if token != "key" {
+		t.Fatalf("auth token %q", token)
+	}
+}
+
+func TestPooledBody(t *testing.T) {
+	buf := bytes.NewBufferString("payload")
+	b := newPooledBody(buf)
+	b.Close()
+	b.Close()
+	if _, err := b.Read(make([]byte, 10)); err != http.ErrBodyReadAfterClose {
+		t.Fatalf("read after close: %v", err)
+	}
+	if n := b.refs.Load(); n != 1 {
+		t.Fatalf("buffer references after close %d, want 1 held by caller", n)
+	}
+}
+
+// TestPostDataHTTP2 sends different payloads concurrently over HTTP/2 and checks that none is mixed
+func TestPostDataHTTP2(t *testing.T) {
+	var mixed atomic.Int32
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var m struct {
+			Receiver string `json:"receiver"`
+			Text     string `json:"text"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&m); err != nil || "t"+m.Receiver != m.Text || r.ProtoMajor != 2 {
+			mixed.Add(1)
+		}
+		fmt.Fprint(w, `{"status":0,"status_message":"ok","message_token":1}`)
+	}))
+	srv.EnableHTTP2 = true
+	srv.StartTLS()
+	defer srv.Close()
+
+	v := New("key", "bot", "", WithBaseURL(srv.URL), WithHTTPClient(srv.Client()))
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				id := fmt.Sprintf("%d-%d", i, j)
+				if _, err := v.SendTextMessage(id, "t"+id); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	if n := mixed.Load(); n > 0 {
+		t.Fatalf("%d payloads mixed or not sent over HTTP/2", n)
+	}
+}
+
+// BenchmarkPostData sends 10k messages per op from 50 goroutines against local stub
+func BenchmarkPostData(b *testing.B) {
+	const (
+		sends   = 10000
+		workers = 50
+	)
+
+	srv := apiStub()
+	defer srv.Close()
+	v := New("key", "bot", "", WithBaseURL(srv.URL))
+	m := v.NewTextMessage("Hello, World!")
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var n, failed int64
+		var wg sync.WaitGroup
+		for w := 0; w < workers; w++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for atomic.AddInt64(&n, 1) <= sends {
+					if _, err := v.sendMessage(v.apiURL("send_message"), m); err != nil {
+						atomic.AddInt64(&failed, 1)
+					}
+				}
+			}()
+		}
+		wg.Wait()
+		if failed > 0 {
+			b.Fatalf("%d of %d sends failed", failed, sends)
+		}
+	}
+	b.ReportMetric(float64(sends*b.N)/b.Elapsed().Seconds(), "sends/s")
+}
@@ -0,0 +1,377 @@
+package viber
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Job is scheduled message, sent once at time At or repeatedly by Cron schedule
+type Job struct {
+	ID       string          `json:"id"`
+	Receiver string          `json:"receiver"`
+	Message  json.RawMessage `json:"message"`
+
+	// At is the next time message will be sent
+	At time.Time `json:"at"`
+
+	// Cron schedule of recurring job and IANA time zone name used for the schedule, e.g. "Europe/Belgrade"
+	Cron     string `json:"cron,omitempty"`
+	Location string `json:"location,omitempty"`
+
+	// Done is set after one time job is sent
+	Done bool `json:"done,omitempty"`
+
+	// Attempts of the current run which failed with temporary error and are retried
+	Attempts int `json:"attempts,omitempty"`
+
+	// result of the last run
+	Runs    int       `json:"runs"`
+	LastRun time.Time `json:"last_run,omitempty"`
+	Token   uint64    `json:"token,omitempty"`
+	Error   string    `json:"error,omitempty"`
+}
+
+// JobStore persists scheduled jobs
+type JobStore interface {
+	// Save job state
+	Save(job Job) error
+	// Delete job
+	Delete(id string) error
+	// Load all saved jobs
+	Load() ([]Job, error)
+}
+
+// FileJobStore is JobStore backed by local append-only file, compacted when opened and as it grows
+type FileJobStore struct {
+	*fileStore[Job]
+}
+
+// OpenFileJobStore opens or creates jobs file at path
+func OpenFileJobStore(path string) (*FileJobStore, error) {
+	s, err := openFileStore(path, func(job Job) string { return job.ID })
+	if err != nil {
+		return nil, err
+	}
+	return &FileJobStore{s}, nil
+}
+
+// ErrJobNotFound is returned for unknown job id
+var ErrJobNotFound = errors.New("viber: scheduled job not found")
+
+// Scheduler sends messages at given time or by recurring schedule.
+// Jobs are persisted in JobStore, so they survive restarts. Jobs missed while
+// the app was down are sent once as soon as Scheduler is started.
+type Scheduler struct {
+	// Limiter for sending rate, nil sends without limit
+	Limiter Limiter
+
+	// MaxAttempts for sending a job message, failed network calls and throttled calls are retried
+	MaxAttempts int
+
+	// RetryDelay before the first retry, doubled for each next attempt
+	RetryDelay time.Duration
+
+	// Sent is called after job message is sent or has failed permanently, with resulting message token or error
+	Sent func(job Job, token uint64, err error)
+
+	v     *Viber
+	store JobStore
+
+	mu   sync.Mutex
+	jobs map[string]*Job
+	wake chan struct{}
+
+	runner runner
+}
+
+// NewScheduler returns scheduler of v which loads jobs from store. Call Start to start sending.
+func (v *Viber) NewScheduler(store JobStore) (*Scheduler, error) {
+	jobs, err := store.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Scheduler{
+		MaxAttempts: 5,
+		RetryDelay:  time.Second,
+		v:           v,
+		store:       store,
+		jobs:        make(map[string]*Job),
+		wake:        make(chan struct{}, 1),
+	}
+	for i := range jobs {
+		job := jobs[i]
+		s.jobs[job.ID] = &job
+	}
+	return s, nil
+}
+
+// SendAt schedules message m to receiver at time t, returns job id
+func (s *Scheduler) SendAt(t time.Time, receiver string, m Message) (string, error) {
+	return s.add(receiver, m, t, "", "")
+}
+
+// SendAfter schedules message m to receiver after duration d, returns job id
+func (s *Scheduler) SendAfter(d time.Duration, receiver string, m Message) (string, error) {
+	return s.add(receiver, m, time.Now().Add(d), "", "")
+}
+
+// SendEvery schedules message m to receiver by cron-like spec (see Schedule) in location loc,
+// nil loc is UTC. Location is saved by name, so it must be loadable with time.LoadLocation,
+// locations created with time.FixedZone are rejected. Returns job id.
+func (s *Scheduler) SendEvery(spec string, loc *time.Location, receiver string, m Message) (string, error) {
+	sched, err := ParseSchedule(spec)
+	if err != nil {
+		return "", err
+	}
+	if loc == nil {
+		loc = time.UTC
+	}
+	// fixed zone may be named after real zone, such as "CET", so offsets are compared as well
+	loaded, err := time.LoadLocation(loc.String())
+	if err != nil {
+		return "", fmt.Errorf("viber: location %q can't be loaded by name: %v", loc.String(), err)
+	}
+	now := time.Now()
+	_, off := now.In(loc).Zone()
+	_, loadedOff := now.In(loaded).Zone()
+	if off != loadedOff {
+		return "", fmt.Errorf("viber: location %q doesn't match time zone with the same name", loc.String())
+	}
+
+	next := sched.Next(time.Now().In(loc))
+	if next.IsZero() {
+		return "", errors.New("viber: schedule " + spec + " never runs")
+	}
+	return s.add(receiver, m, next, spec, loc.String())
+}
+
+// add job for message m, with CheckMedia set media is checked by Preflight when job is scheduled
+func (s *Scheduler) add(receiver string, m Message, at time.Time, spec, loc string) (string, error) {
+	if s.v.CheckMedia {
+		if err := s.v.Preflight(m); err != nil {
+			return "", err
+		}
+	}
+	m.SetReceiver(receiver)
+	setMinAPIVersion(m)
+	b, err := json.Marshal(m)
+	if err != nil {
+		return "", err
+	}
+
+	job := Job{
+		ID:       newID(),
+		Receiver: receiver,
+		Message:  b,
+		At:       at,
+		Cron:     spec,
+		Location: loc,
+	}
+	if err := s.store.Save(job); err != nil {
+		return "", err
+	}
+
+	s.mu.Lock()
+	s.jobs[job.ID] = &job
+	s.mu.Unlock()
+
+	s.signal()
+	return job.ID, nil
+}
+
+// Job returns current state of scheduled job
+func (s *Scheduler) Job(id string) (Job, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job, ok := s.jobs[id]
+	if !ok {
+		return Job{}, false
+	}
+	return *job, true
+}
+
+// Jobs returns all jobs, including sent one time jobs
+func (s *Scheduler) Jobs() []Job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	jobs := make([]Job, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		jobs = append(jobs, *job)
+	}
+	return jobs
+}
+
+// Cancel removes the job, its message won't be sent anymore
+func (s *Scheduler) Cancel(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.jobs[id]; !ok {
+		return ErrJobNotFound
+	}
+	if err := s.store.Delete(id); err != nil {
+		return err
+	}
+	delete(s.jobs, id)
+	return nil
+}
+
+// Purge removes done jobs which last run before t
+func (s *Scheduler) Purge(t time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, job := range s.jobs {
+		if job.Done && job.LastRun.Before(t) {
+			if err := s.store.Delete(id); err != nil {
+				return err
+			}
+			delete(s.jobs, id)
+		}
+	}
+	return nil
+}
+
+// Start sending scheduled messages in background
+func (s *Scheduler) Start() {
+	s.runner.start(s.run)
+}
+
+// Stop scheduler and wait for message being sent to finish
+func (s *Scheduler) Stop() {
+	s.runner.stop()
+}
+
+func (s *Scheduler) run(ctx context.Context) {
+	for {
+		job, wait := s.next()
+		if job == nil {
+			t := time.NewTimer(wait)
+			select {
+			case <-ctx.Done():
+			case <-s.wake:
+			case <-t.C:
+			}
+			t.Stop()
+			if ctx.Err() != nil {
+				return
+			}
+			continue
+		}
+
+		if s.Limiter != nil {
+			if err := s.Limiter.Wait(ctx); err != nil {
+				return
+			}
+		}
+
+		token, err := s.v.sendMessage(s.v.apiURL("send_message"), job.Message)
+		s.update(job, token, err)
+	}
+}
+
+// maxSchedulerWait between checks when no job is due, so clock changes are picked up
+const maxSchedulerWait = time.Minute
+
+// next returns copy of due job, or time to wait for the earliest job
+func (s *Scheduler) next() (*Job, time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	wait := maxSchedulerWait
+	for _, job := range s.jobs {
+		if job.Done {
+			continue
+		}
+		if !job.At.After(now) {
+			c := *job
+			return &c, 0
+		}
+		if d := job.At.Sub(now); d < wait {
+			wait = d
+		}
+	}
+	return nil, wait
+}
+
+// update job after it was run, recurring job is moved to its next time.
+// Run failed with temporary error is retried with backoff.
+func (s *Scheduler) update(run *Job, token uint64, err error) {
+	s.mu.Lock()
+	job, ok := s.jobs[run.ID]
+	if !ok {
+		// canceled while sending
+		s.mu.Unlock()
+		return
+	}
+
+	now := time.Now()
+	if err != nil && temporary(err) && job.Attempts+1 < s.MaxAttempts {
+		job.Attempts++
+		job.Error = err.Error()
+		job.At = now.Add(s.RetryDelay << uint(job.Attempts-1))
+		if serr := s.store.Save(*job); serr != nil {
+			s.v.logger().Error("scheduled job not saved", slog.String("id", job.ID), slog.String("error", serr.Error()))
+		}
+		s.mu.Unlock()
+		return
+	}
+
+	job.Attempts = 0
+	job.Runs++
+	job.LastRun = now
+	job.Token = token
+	job.Error = ""
+	if err != nil {
+		job.Error = err.Error()
+	}
+
+	if job.Cron == "" {
+		job.Done = true
+	} else if next, nerr := nextRun(job, now); nerr != nil {
+		job.Done = true
+		s.v.logger().Error("recurring job stopped", slog.String("id", job.ID), slog.String("error", nerr.Error()))
+	} else if next.IsZero() {
+		job.Done = true
+	} else {
+		job.At = next
+	}
+
+	c := *job
+	if serr := s.store.Save(c); serr != nil {
+		s.v.logger().Error("scheduled job not saved", slog.String("id", c.ID), slog.String("error", serr.Error()))
+	}
+	s.mu.Unlock()
+
+	if s.Sent != nil {
+		s.Sent(c, token, err)
+	}
+}
+
+// nextRun of recurring job after t
+func nextRun(job *Job, t time.Time) (time.Time, error) {
+	sched, err := ParseSchedule(job.Cron)
+	if err != nil {
+		return time.Time{}, err
+	}
+	loc, err := time.LoadLocation(job.Location)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return sched.Next(t.In(loc)), nil
+}
+
+// signal run loop that job is added
+func (s *Scheduler) signal() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,145 @@
+package viber
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestScheduleNext(t *testing.T) {
+	tests := []struct {
+		spec string
+		from string
+		want string
+	}{
+		{"0 9 * * 1", "2024-01-03 10:00", "2024-01-08 09:00"},
+		{"*/30 8-17 * * 1-5", "2024-01-05 17:45", "2024-01-08 08:00"},
+		{"15 10 1 * *", "2024-01-01 10:15", "2024-02-01 10:15"},
+		{"0 0 29 2 *", "2024-03-01 00:00", "2028-02-29 00:00"},
+	}
+	for _, tt := range tests {
+		s, err := ParseSchedule(tt.spec)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.spec, err)
+		}
+		from, _ := time.Parse("2006-01-02 15:04", tt.from)
+		if got := s.Next(from).Format("2006-01-02 15:04"); got != tt.want {
+			t.Errorf("%s from %s: got %s, want %s", tt.spec, tt.from, got, tt.want)
+		}
+	}
+
+	for _, spec := range []string{"", "* * * *", "60 * * * *", "* 5-1 * * *", "*/0 * * * *"} {
+		if _, err := ParseSchedule(spec); err == nil {
+			t.Errorf("%q: expected error", spec)
+		}
+	}
+}
+
+func TestSchedulerRejectsFixedZone(t *testing.T) {
+	store, err := OpenFileJobStore(filepath.Join(t.TempDir(), "jobs.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	v := New("key", "bot", "")
+	s, _ := v.NewScheduler(store)
+
+	if _, err := s.SendEvery("0 9 * * *", time.FixedZone("UTC+1", 3600), "user", v.NewTextMessage("hi")); err == nil {
+		t.Fatal("fixed zone location accepted")
+	}
+	if _, err := s.SendEvery("0 9 * * *", nil, "user", v.NewTextMessage("hi")); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSchedulerSendAndPurge(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"status":0,"status_message":"ok","message_token":7}`)
+	}))
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "jobs.log")
+	store, err := OpenFileJobStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := New("key", "bot", "", WithBaseURL(srv.URL))
+	s, _ := v.NewScheduler(store)
+	sent := make(chan Job, 1)
+	s.Sent = func(job Job, token uint64, err error) { sent <- job }
+
+	id, err := s.SendAfter(10*time.Millisecond, "user", v.NewTextMessage("hi"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Start()
+	select {
+	case job := <-sent:
+		if job.ID != id || !job.Done || job.Token != 7 {
+			t.Fatalf("unexpected job %+v", job)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("job not sent")
+	}
+	s.Stop()
+
+	if err := s.Purge(time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Jobs()) != 0 {
+		t.Fatal("done job not purged")
+	}
+	store.Close()
+
+	store, _ = OpenFileJobStore(path)
+	defer store.Close()
+	if jobs, _ := store.Load(); len(jobs) != 0 {
+		t.Fatalf("purged job still in file: %+v", jobs)
+	}
+}
+
+func TestSchedulerRetriesThrottledJob(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			fmt.Fprint(w, `{"status":12,"status_message":"tooManyRequests"}`)
+			return
+		}
+		fmt.Fprint(w, `{"status":0,"status_message":"ok","message_token":7}`)
+	}))
+	defer srv.Close()
+
+	store, err := OpenFileJobStore(filepath.Join(t.TempDir(), "jobs.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	v := New("key", "bot", "", WithBaseURL(srv.URL))
+	s, _ := v.NewScheduler(store)
+	s.RetryDelay = 10 * time.Millisecond
+	s.Limiter = Every(time.Millisecond)
+	sent := make(chan Job, 2)
+	s.Sent = func(job Job, token uint64, err error) { sent <- job }
+
+	if _, err := s.SendAfter(0, "user", v.NewTextMessage("hi")); err != nil {
+		t.Fatal(err)
+	}
+	s.Start()
+	defer s.Stop()
+
+	select {
+	case job := <-sent:
+		if !job.Done || job.Token != 7 || job.Runs != 1 || job.Attempts != 0 || job.Error != "" {
+			t.Fatalf("unexpected job %+v", job)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("throttled job not retried")
+	}
+	if n := atomic.LoadInt32(&calls); n != 2 {
+		t.Fatalf("%d send calls, want 2", n)
+	}
+}
@@ -0,0 +1,126 @@
+package viber
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"io"
+
+	// decoders of picture message formats
+	_ "image/gif"
+	_ "image/png"
+)
+
+// Thumbnail limits recommended by Viber
+const (
+	ThumbnailSize     = 400
+	MaxThumbnailBytes = 100 << 10
+)
+
+// MaxImagePixels of image Thumbnail decodes, since decoded image takes 4 or more bytes per pixel
+const MaxImagePixels = 40 << 20
+
+// Errors returned by Thumbnail
+var (
+	ErrThumbnailTooLarge = errors.New("viber: thumbnail too large")
+	ErrImageTooLarge     = errors.New("viber: image dimensions too large")
+)
+
+// Thumbnail returns JPEG thumbnail of JPEG, PNG or GIF image read from r, scaled down to fit
+// ThumbnailSize x ThumbnailSize and compressed under MaxThumbnailBytes.
+// Images larger than MaxImagePixels are rejected before they are decoded.
+func Thumbnail(r io.Reader) ([]byte, error) {
+	// header read by DecodeConfig is replayed to Decode
+	var head bytes.Buffer
+	c, _, err := image.DecodeConfig(io.TeeReader(r, &head))
+	if err != nil {
+		return nil, err
+	}
+	if c.Width <= 0 || c.Height <= 0 || int64(c.Width)*int64(c.Height) > MaxImagePixels {
+		return nil, ErrImageTooLarge
+	}
+	img, _, err := image.Decode(io.MultiReader(&head, r))
+	if err != nil {
+		return nil, err
+	}
+
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w > ThumbnailSize || h > ThumbnailSize {
+		if w >= h {
+			w, h = ThumbnailSize, max(1, h*ThumbnailSize/w)
+		} else {
+			w, h = max(1, w*ThumbnailSize/h), ThumbnailSize
+		}
+	}
+	thumb := scale(img, w, h)
+
+	var buf bytes.Buffer
+	for q := 85; q >= 25; q -= 15 {
+		buf.Reset()
+		if err := jpeg.Encode(&buf, thumb, &jpeg.Options{Quality: q}); err != nil {
+			return nil, err
+		}
+		if buf.Len() <= MaxThumbnailBytes {
+			return buf.Bytes(), nil
+		}
+	}
+	return nil, ErrThumbnailTooLarge
+}
+
+// scale src to w x h by averaging source pixels, transparent pixels are blended over white
+func scale(src image.Image, w, h int) *image.RGBA {
+	b := src.Bounds()
+	sw, sh := b.Dx(), b.Dy()
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+
+	for y := 0; y < h; y++ {
+		y0 := b.Min.Y + y*sh/h
+		y1 := max(y0+1, b.Min.Y+(y+1)*sh/h)
+		for x := 0; x < w; x++ {
+			x0 := b.Min.X + x*sw/w
+			x1 := max(x0+1, b.Min.X+(x+1)*sw/w)
+
+			var r, g, bl, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					white := uint64(0xffff - ca)
+					r += uint64(cr) + white
+					g += uint64(cg) + white
+					bl += uint64(cb) + white
+					n++
+				}
+			}
+			dst.SetRGBA(x, y, color.RGBA{uint8(r / n >> 8), uint8(g / n >> 8), uint8(bl / n >> 8), 0xff})
+		}
+	}
+	return dst
+}
+
+// AttachThumbnail generates thumbnail from image read from r, uploads it to MediaStore
+// and sets it as Thumbnail of picture or video message m. For video, r is a frame or poster image.
+func (v *Viber) AttachThumbnail(m Message, r io.Reader) error {
+	var thumbURL *string
+	switch m := m.(type) {
+	case *PictureMessage:
+		thumbURL = &m.Thumbnail
+	case *VideoMessage:
+		thumbURL = &m.Thumbnail
+	default:
+		return errors.New("viber: thumbnail is supported only for picture and video messages")
+	}
+
+	b, err := Thumbnail(r)
+	if err != nil {
+		return err
+	}
+	u, _, err := v.upload("thumbnail.jpg", "image/jpeg", bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	*thumbURL = u
+	return nil
+}
@@ -0,0 +1,91 @@
+package viber
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"math/rand"
+	"testing"
+)
+
+func TestThumbnailScale(t *testing.T) {
+	tests := []struct {
+		w, h         int
+		wantW, wantH int
+	}{
+		{1200, 600, 400, 200},
+		{300, 900, 133, 400},
+		{100, 50, 100, 50},
+		{4000, 1, 400, 1},
+	}
+	for _, tt := range tests {
+		b, err := Thumbnail(bytes.NewReader(pngImage(tt.w, tt.h)))
+		if err != nil {
+			t.Fatalf("%dx%d: %v", tt.w, tt.h, err)
+		}
+		c, err := jpeg.DecodeConfig(bytes.NewReader(b))
+		if err != nil {
+			t.Fatalf("%dx%d: thumbnail is not jpeg: %v", tt.w, tt.h, err)
+		}
+		if c.Width != tt.wantW || c.Height != tt.wantH {
+			t.Errorf("%dx%d: thumbnail %dx%d, want %dx%d", tt.w, tt.h, c.Width, c.Height, tt.wantW, tt.wantH)
+		}
+	}
+}
+
+func TestThumbnailBytes(t *testing.T) {
+	// noise compresses poorly, so quality has to be lowered to fit the limit
+	img := image.NewRGBA(image.Rect(0, 0, ThumbnailSize, ThumbnailSize))
+	rnd := rand.New(rand.NewSource(1))
+	for i := range img.Pix {
+		img.Pix[i] = uint8(rnd.Intn(256))
+	}
+	var src bytes.Buffer
+	jpeg.Encode(&src, img, &jpeg.Options{Quality: 100})
+
+	b, err := Thumbnail(&src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b) > MaxThumbnailBytes {
+		t.Fatalf("thumbnail %d bytes, limit %d", len(b), MaxThumbnailBytes)
+	}
+}
+
+func TestThumbnailTransparency(t *testing.T) {
+	var src bytes.Buffer
+	png.Encode(&src, image.NewNRGBA(image.Rect(0, 0, 10, 10)))
+	b, err := Thumbnail(&src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	thumb, _ := jpeg.Decode(bytes.NewReader(b))
+	if r, g, bl, _ := thumb.At(5, 5).RGBA(); r>>8 < 0xf0 || g>>8 < 0xf0 || bl>>8 < 0xf0 {
+		t.Fatalf("transparent pixel not blended over white: %v", color.RGBAModel.Convert(thumb.At(5, 5)))
+	}
+}
+
+func TestThumbnailRejectsHugeImage(t *testing.T) {
+	// few bytes of PNG header declaring 50000x50000 image
+	if _, err := Thumbnail(bytes.NewReader(pngHeader(50000, 50000))); err != ErrImageTooLarge {
+		t.Fatalf("huge image: %v", err)
+	}
+}
+
+// pngHeader returns PNG signature and IHDR chunk of 8-bit grayscale w x h image
+func pngHeader(w, h uint32) []byte {
+	ihdr := make([]byte, 17)
+	copy(ihdr, "IHDR")
+	binary.BigEndian.PutUint32(ihdr[4:], w)
+	binary.BigEndian.PutUint32(ihdr[8:], h)
+	ihdr[12] = 8 // bit depth, color type, compression, filter and interlace are 0
+
+	b := []byte("\x89PNG\r\n\x1a\n")
+	b = binary.BigEndian.AppendUint32(b, 13)
+	b = append(b, ihdr...)
+	return binary.BigEndian.AppendUint32(b, crc32.ChecksumIEEE(ihdr))
+}
@@ -0,0 +1,55 @@
+package viber
+
+import "context"
+
+// Tracer starts spans around webhook events, event callbacks and API calls.
+// It is modeled after OpenTelemetry tracer, so it can be implemented as thin adapter to any tracing library.
+type Tracer interface {
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Span of traced operation
+type Span interface {
+	SetAttribute(key string, value interface{})
+	RecordError(err error)
+	End()
+}
+
+type noopSpan struct{}
+
+func (noopSpan) SetAttribute(key string, value interface{}) {}
+func (noopSpan) RecordError(err error)                      {}
+func (noopSpan) End()                                       {}
+
+// startSpan with v.Tracer, or noop span if tracing is disabled
+func (v *Viber) startSpan(ctx context.Context, name string) (context.Context, Span) {
+	if v.Tracer == nil {
+		return ctx, noopSpan{}
+	}
+	return v.Tracer.Start(ctx, name)
+}
+
+// Context of v used for API calls. With Tracer set, Viber passed to event callbacks carries context
+// of the callback span, so replies sent from the callback are traced as children of the inbound event.
+func (v *Viber) Context() context.Context {
+	if v.ctx != nil {
+		return v.ctx
+	}
+	return context.Background()
+}
+
+// WithContext returns shallow copy of v which uses ctx for API calls
+func (v *Viber) WithContext(ctx context.Context) *Viber {
+	c := *v
+	c.ctx = ctx
+	return &c
+}
+
+// callbackBot is passed to event callbacks. Without Tracer it is v itself, so callbacks can compare
+// bots by pointer. With Tracer it is a copy of v carrying callback span context.
+func (v *Viber) callbackBot(ctx context.Context) *Viber {
+	if v.Tracer == nil {
+		return v
+	}
+	return v.WithContext(ctx)
+}
@@ -0,0 +1,37 @@
+package viber
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+type spanKey struct{}
+
+// testTracer puts span name into context
+type testTracer struct{}
+
+func (testTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	return context.WithValue(ctx, spanKey{}, name), noopSpan{}
+}
+
+func TestCallbackBot(t *testing.T) {
+	v := New("key", "bot", "")
+	v.Dispatcher = syncDispatcher{}
+	var got *Viber
+	v.Subscribed = func(cb *Viber, u User, token uint64, t time.Time) { got = cb }
+
+	postWebhook(v, "key", `{"event":"subscribed","timestamp":1,"message_token":1,"user":{"id":"u1"}}`)
+	if got != v {
+		t.Fatal("callback without Tracer should receive the bot itself")
+	}
+
+	v.Tracer = testTracer{}
+	postWebhook(v, "key", `{"event":"subscribed","timestamp":1,"message_token":2,"user":{"id":"u1"}}`)
+	if got == v {
+		t.Fatal("callback with Tracer should receive copy of the bot")
+	}
+	if span := got.Context().Value(spanKey{}); span != "viber.callback" {
+		t.Fatalf("callback context span %v, want viber.callback", span)
+	}
+}
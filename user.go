@@ -1,6 +1,10 @@
 package viber
 
-import "encoding/json"
+import (
+	"encoding/json"
+	"sync"
+	"time"
+)
 
 // User struct as part of UserDetails
 type User struct {
@@ -34,14 +38,52 @@ type online struct {
 
 // UserOnline response struct
 type UserOnline struct {
-	ID                  string `json:"id"`
-	OnlineStatus        int    `json:"online_status"`
-	OnlineStatusMessage string `json:"online_status_message"`
-	LastOnline          int64  `json:"last_online,omitempty"`
+	ID                  string       `json:"id"`
+	OnlineStatus        OnlineStatus `json:"online_status"`
+	OnlineStatusMessage string       `json:"online_status_message"`
+	LastOnline          Timestamp    `json:"last_online,omitempty"`
 }
 
-// UserDetails of user id
+// OnlineStatus of user
+type OnlineStatus int
+
+// OnlineStatus values
+const (
+	Online      = OnlineStatus(0)
+	Offline     = OnlineStatus(1)
+	Undisclosed = OnlineStatus(2)
+	TryLater    = OnlineStatus(3)
+)
+
+// MaxOnlineIDs per get_online call, UserOnline splits longer lists into batches
+const MaxOnlineIDs = 100
+
+// maxOnlineBatches sent concurrently by UserOnline
+const maxOnlineBatches = 4
+
+// UserDetails of user id. With UserCache set, cached details are returned if they are not expired,
+// and expired or partial details from events are returned when Viber throttles the call.
 func (v *Viber) UserDetails(id string) (UserDetails, error) {
+	if v.UserCache != nil {
+		if e, ok := v.UserCache.get(id); ok && e.full && time.Since(e.updated) <= v.UserCache.ttl {
+			return UserDetails{StatusMessage: "ok", User: e.user}, nil
+		}
+	}
+
+	u, err := v.userDetails(id)
+	if v.UserCache != nil {
+		if err == nil {
+			v.UserCache.Set(u.User)
+		} else if ErrorStatus(err) == StatusTooManyRequests {
+			if e, ok := v.UserCache.get(id); ok {
+				return UserDetails{StatusMessage: "ok", User: e.user}, nil
+			}
+		}
+	}
+	return u, err
+}
+
+func (v *Viber) userDetails(id string) (UserDetails, error) {
 	/*
 				b := []byte(`{
 				"status": 0,
@@ -73,7 +115,7 @@ func (v *Viber) UserDetails(id string) (UserDetails, error) {
 		ID: id,
 	}
 
-	b, err := v.PostData("https://chatapi.viber.com/pa/get_user_details", s)
+	b, err := v.PostData(v.apiURL("get_user_details"), s)
 	if err != nil {
 		return u, err
 	}
@@ -91,15 +133,51 @@ func (v *Viber) UserDetails(id string) (UserDetails, error) {
 
 }
 
-// UserOnline status
+// UserOnline status of users. More than MaxOnlineIDs ids are queried in concurrent batches,
+// results are returned in order of ids. Error is returned if any of batches fails.
 func (v *Viber) UserOnline(ids []string) ([]UserOnline, error) {
+	batches := make([][]UserOnline, (len(ids)+MaxOnlineIDs-1)/MaxOnlineIDs)
+	errs := make([]error, len(batches))
+	sem := make(chan struct{}, maxOnlineBatches)
+	var wg sync.WaitGroup
+	for i := range batches {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			batch := ids[i*MaxOnlineIDs : min((i+1)*MaxOnlineIDs, len(ids))]
+			batches[i], errs[i] = v.userOnline(batch)
+		}(i)
+	}
+	wg.Wait()
+
+	users := make(map[string]UserOnline, len(ids))
+	for i, batch := range batches {
+		if errs[i] != nil {
+			return []UserOnline{}, errs[i]
+		}
+		for _, u := range batch {
+			users[u.ID] = u
+		}
+	}
+	result := make([]UserOnline, 0, len(ids))
+	for _, id := range ids {
+		if u, ok := users[id]; ok {
+			result = append(result, u)
+		}
+	}
+	return result, nil
+}
+
+func (v *Viber) userOnline(ids []string) ([]UserOnline, error) {
 	var uo online
 	req := struct {
 		IDs []string `json:"ids"`
 	}{
 		IDs: ids,
 	}
-	b, err := v.PostData("https://chatapi.viber.com/pa/get_online", req)
+	b, err := v.PostData(v.apiURL("get_online"), req)
 	if err != nil {
 		return []UserOnline{}, err
 	}
@@ -0,0 +1,31 @@
+package viber
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestUserOnlineOrder(t *testing.T) {
+	srv := onlineStub(func(id string) OnlineStatus { return Offline })
+	defer srv.Close()
+	v := New("key", "bot", "", WithBaseURL(srv.URL))
+
+	for _, n := range []int{3, 250} {
+		var ids []string
+		for i := 0; i < n; i++ {
+			ids = append(ids, fmt.Sprint("u", i))
+		}
+		users, err := v.UserOnline(ids)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(users) != n {
+			t.Fatalf("%d users, want %d", len(users), n)
+		}
+		for i, u := range users {
+			if u.ID != ids[i] {
+				t.Fatalf("%d ids: user %d is %s, want %s", n, i, u.ID, ids[i])
+			}
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package viber
+
+import (
+	"container/list"
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// UserCache keeps user details by user id, so UserDetails doesn't hit Viber API,
+// which allows only a few get_user_details calls per user in several hours.
+// Cache is filled by UserDetails calls and by user data from subscribed,
+// conversation_started and message events.
+type UserCache struct {
+	size int
+	ttl  time.Duration
+
+	mu sync.Mutex
+	ll *list.List
+	m  map[string]*list.Element
+}
+
+type userEntry struct {
+	user    User
+	updated time.Time
+
+	// full is set for details from get_user_details, events carry only part of user details
+	full bool
+}
+
+// NewUserCache returns UserCache which keeps at most size users. Details older than ttl are
+// fetched again, but are still used if Viber throttles get_user_details calls.
+func NewUserCache(size int, ttl time.Duration) *UserCache {
+	return &UserCache{
+		size: size,
+		ttl:  ttl,
+		ll:   list.New(),
+		m:    make(map[string]*list.Element),
+	}
+}
+
+// Get returns cached user which is not older than cache ttl
+func (c *UserCache) Get(id string) (User, bool) {
+	e, ok := c.get(id)
+	if !ok || time.Since(e.updated) > c.ttl {
+		return User{}, false
+	}
+	return e.user, true
+}
+
+// Set user details
+func (c *UserCache) Set(u User) {
+	c.put(u, true)
+}
+
+// Remove user from cache
+func (c *UserCache) Remove(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.m[id]; ok {
+		c.ll.Remove(el)
+		delete(c.m, id)
+	}
+}
+
+func (c *UserCache) get(id string) (userEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.m[id]
+	if !ok {
+		return userEntry{}, false
+	}
+	c.ll.MoveToFront(el)
+	return *el.Value.(*userEntry), true
+}
+
+// put user, partial details from events update existing entry without refreshing full details
+func (c *UserCache) put(u User, full bool) {
+	if u.ID == "" {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.m[u.ID]; ok {
+		e := el.Value.(*userEntry)
+		c.ll.MoveToFront(el)
+		if full || !e.full {
+			e.user, e.updated, e.full = u, time.Now(), full
+			return
+		}
+		e.user.Name = u.Name
+		e.user.Avatar = u.Avatar
+		if u.Country != "" {
+			e.user.Country = u.Country
+		}
+		if u.Language != "" {
+			e.user.Language = u.Language
+		}
+		if u.APIVersion != 0 {
+			e.user.APIVersion = u.APIVersion
+		}
+		return
+	}
+
+	c.m[u.ID] = c.ll.PushFront(&userEntry{user: u, updated: time.Now(), full: full})
+	for c.ll.Len() > c.size {
+		el := c.ll.Back()
+		c.ll.Remove(el)
+		delete(c.m, el.Value.(*userEntry).user.ID)
+	}
+}
+
+// cacheEventUser stores user data from subscribed, conversation_started and message events
+func (v *Viber) cacheEventUser(e *event) {
+	if v.UserCache == nil {
+		return
+	}
+
+	var raw json.RawMessage
+	switch e.Event {
+	case EventSubscribed, EventConversationStarted:
+		raw = e.User
+	case EventMessage:
+		raw = e.Sender
+	default:
+		return
+	}
+	var u User
+	if err := json.Unmarshal(raw, &u); err == nil {
+		v.UserCache.put(u, false)
+	}
+}
@@ -0,0 +1,44 @@
+package viber
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestUserDetailsCache(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls > 1 {
+			fmt.Fprint(w, `{"status":12,"status_message":"tooManyRequests"}`)
+			return
+		}
+		fmt.Fprint(w, `{"status":0,"status_message":"ok","user":{"id":"u1","name":"John","primary_device_os":"Android"}}`)
+	}))
+	defer srv.Close()
+
+	v := New("key", "bot", "", WithBaseURL(srv.URL))
+	v.UserCache = NewUserCache(10, 20*time.Millisecond)
+
+	if u, err := v.UserDetails("u1"); err != nil || u.Name != "John" {
+		t.Fatalf("details %+v, %v", u, err)
+	}
+	if u, err := v.UserDetails("u1"); err != nil || u.Name != "John" || calls != 1 {
+		t.Fatalf("cached details %+v, %v, %d calls", u, err, calls)
+	}
+
+	// event updates name, expired details are returned when throttled
+	postWebhook(v, "key", `{"event":"subscribed","timestamp":1,"message_token":1,"user":{"id":"u1","name":"Johnny"}}`)
+	time.Sleep(30 * time.Millisecond)
+	u, err := v.UserDetails("u1")
+	if err != nil || u.Name != "Johnny" || u.PrimaryDeviceOs != "Android" || calls != 2 {
+		t.Fatalf("throttled details %+v, %v, %d calls", u, err, calls)
+	}
+
+	if _, err := v.UserDetails("u2"); ErrorStatus(err) != StatusTooManyRequests {
+		t.Fatalf("uncached throttled user: %v", err)
+	}
+}
@@ -1,12 +1,14 @@
 package viber
 
 import (
+	"context"
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
-	"io/ioutil"
+	"io"
 	"log"
+	"log/slog"
 	"net/http"
 	"regexp"
 	"strings"
@@ -20,7 +22,7 @@ type Sender struct {
 }
 
 type event struct {
-	Event        string    `json:"event"`
+	Event        EventType `json:"event"`
 	Timestamp    Timestamp `json:"timestamp"`
 	MessageToken uint64    `json:"message_token,omitempty"`
 	UserID       string    `json:"user_id,omitempty"`
@@ -44,6 +46,17 @@ type Viber struct {
 	AppKey string
 	Sender Sender
 
+	// BaseURL of Viber REST API, DefaultBaseURL if not set
+	BaseURL string
+
+	// VerifyKeys are additional auth tokens accepted for webhook signatures, besides AppKey.
+	// AppKey is always used for API calls, so during token rotation set new token as AppKey
+	// and keep previous one in VerifyKeys until Viber signs all callbacks with the new one.
+	VerifyKeys []string
+
+	// KeyMatched is called with the auth token which verified webhook request signature
+	KeyMatched func(v *Viber, key string)
+
 	// event methods
 	ConversationStarted func(v *Viber, u User, conversationType, context string, subscribed bool, token uint64, t time.Time) Message
 	Message             func(v *Viber, u User, m Message, token uint64, t time.Time)
@@ -52,158 +65,406 @@ type Viber struct {
 	Delivered           func(v *Viber, userID string, token uint64, t time.Time)
 	Seen                func(v *Viber, userID string, token uint64, t time.Time)
 	Failed              func(v *Viber, userID string, token uint64, descr string, t time.Time)
+	WebhookVerified     func(v *Viber, token uint64, t time.Time)
+	ClientStatus        func(v *Viber, e ClientStatusEvent)
+	Action              func(v *Viber, e ActionEvent)
+
+	// Unknown is called for events and message types which package doesn't decode, with raw request body
+	Unknown func(v *Viber, eventName string, raw json.RawMessage)
+
+	// Dedup store is consulted before dispatching events, so retried callbacks are processed only once.
+	// Events are marked once they are decoded and before they are dispatched, mark is removed if dispatch fails.
+	// New sets in-memory TokenCache, nil disables deduplication.
+	Dedup DedupStore
+
+	// MaxEventAge rejects events with timestamp older than MaxEventAge or more than MaxEventAge in the future,
+	// so captured requests can't be replayed. Replays within the window are dropped by Dedup store,
+	// so keep Dedup TTL longer than MaxEventAge. Zero disables the check.
+	MaxEventAge time.Duration
+
+	// MaxBodySize of webhook request in bytes, 1MB if not set
+	MaxBodySize int64
+
+	// Dispatcher runs event callbacks, nil runs each callback in new goroutine
+	Dispatcher Dispatcher
+
+	// OnError is called when webhook request is rejected or can't be processed,
+	// or when welcome message returned by ConversationStarted can't be displayed by user's client
+	OnError func(v *Viber, err *WebhookError)
+
+	// Metrics instrumentation, nil disables it
+	Metrics Metrics
+
+	// Tracer starts spans for webhook events, callbacks and API calls, nil disables tracing.
+	// With Tracer set, callbacks receive copy of the bot made by WithContext instead of the bot itself,
+	// so replies are traced as children of the event. Use AppKey to identify the bot in callbacks then.
+	Tracer Tracer
+
+	// Logger for package activities and errors, global Log is used if not set
+	Logger *slog.Logger
+
+	// Redact omits raw request and response bodies from logs and hashes user ids,
+	// since bodies contain message texts, user names and phone numbers
+	Redact bool
+
+	// UserCache is used by UserDetails and filled from events, nil disables caching
+	UserCache *UserCache
+
+	// MediaStore hosts media uploaded by UploadPictureMessage, UploadVideoMessage and UploadFileMessage
+	MediaStore MediaStore
+
+	// CheckMedia runs Preflight check of picture, video and file messages before they are sent,
+	// queued or scheduled
+	CheckMedia bool
 
 	// client for sending messages
 	client *http.Client
+
+	// ownClient is set when client is configured with WithHTTPClient or WithTransport, so Mux keeps it
+	ownClient bool
+
+	// mediaClient fetches media from other hosts, set by WithMediaTransport
+	mediaClient *http.Client
+
+	// ctx for API calls, set by WithContext
+	ctx context.Context
 }
 
 var (
-	// Log errors, set to logger if you want to log package activities and errors
-	Log               = log.New(ioutil.Discard, "Viber >>", 0)
+	// Log errors, set to logger if you want to log package activities and errors.
+	// Used for Viber apps without Logger set.
+	Log               = log.New(io.Discard, "Viber >>", 0)
 	regexpPeekMsgType = regexp.MustCompile("\"type\":\\s*\"([^\"]+)\"")
 )
 
-// New returns Viber app with specified app key and default sender
+// defaults for TokenCache used by New and webhook body size
+const (
+	dedupSize = 10000
+	dedupTTL  = time.Hour
+
+	defaultMaxBodySize = 1 << 20
+)
+
+// New returns Viber app with specified app key, default sender and options applied
 // You can also create *VIber{} struct directly
-func New(appKey, senderName, senderAvatar string) *Viber {
-	return &Viber{
+func New(appKey, senderName, senderAvatar string, opts ...Option) *Viber {
+	v := &Viber{
 		AppKey: appKey,
 		Sender: Sender{
 			Name:   senderName,
 			Avatar: senderAvatar,
 		},
-		client: &http.Client{},
+		Dedup:  NewTokenCache(dedupSize, dedupTTL),
+		client: newHTTPClient(),
 	}
+	for _, opt := range opts {
+		opt(v)
+	}
+	return v
 }
 
 // ServeHTTP
 // https://developers.viber.com/docs/api/rest-bot-api/#callbacks
 func (v *Viber) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-
-	body, err := ioutil.ReadAll(r.Body)
-	r.Body.Close()
+	body, err := readBody(r, v.MaxBodySize)
 	if err != nil {
-		Log.Println(err)
+		status := http.StatusBadRequest
+		if err == ErrBodyTooLarge {
+			status = http.StatusRequestEntityTooLarge
+		}
+		v.fail(w, status, body, err)
 		return
 	}
 
-	Log.Println("Received from Viber:", string(body))
+	v.serve(r.Context(), w, body, r.Header.Get("X-Viber-Content-Signature"))
+}
+
+// serve webhook request body with signature
+func (v *Viber) serve(ctx context.Context, w http.ResponseWriter, body []byte, signature string) {
+	v.logBody("webhook request", body)
 
-	if !v.checkHMAC(body, r.Header.Get("X-Viber-Content-Signature")) {
-		Log.Println("X-Viber-Content-Signature doesn't match")
+	key, ok := v.checkHMAC(body, signature)
+	if !ok {
+		if v.Metrics != nil {
+			v.Metrics.SignatureFailure()
+		}
+		v.fail(w, http.StatusUnauthorized, body, ErrSignature)
 		return
 	}
+	if key != v.AppKey {
+		v.logger().Info("signature matched one of VerifyKeys, not AppKey")
+	}
+	if v.KeyMatched != nil {
+		v.KeyMatched(v, key)
+	}
+
+	v.handleEvent(ctx, w, body)
+}
 
+// readBody of webhook request limited to max bytes, or to default size if max is not set
+func readBody(r *http.Request, max int64) ([]byte, error) {
+	defer r.Body.Close()
+
+	if max <= 0 {
+		max = defaultMaxBodySize
+	}
+	if r.ContentLength > max {
+		return nil, ErrBodyTooLarge
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, max+1))
+	if err != nil {
+		return body, err
+	}
+	if int64(len(body)) > max {
+		return body[:max], ErrBodyTooLarge
+	}
+	return body, nil
+}
+
+// fail responds with HTTP status code and reports the error to OnError
+func (v *Viber) fail(w http.ResponseWriter, status int, body []byte, err error) {
+	v.logger().Warn("webhook request rejected", slog.Int("status", status), slog.String("error", err.Error()))
+	http.Error(w, http.StatusText(status), status)
+	if v.OnError != nil {
+		v.OnError(v, &WebhookError{StatusCode: status, Body: body, Err: err})
+	}
+}
+
+// handleEvent decodes verified webhook body and dispatches the event to callbacks.
+// Callbacks outlive the request, so event context is detached from request cancelation.
+func (v *Viber) handleEvent(ctx context.Context, w http.ResponseWriter, body []byte) {
 	var e event
 	if err := json.Unmarshal(body, &e); err != nil {
-		Log.Println(err)
+		v.fail(w, http.StatusBadRequest, body, err)
 		return
 	}
 
+	ctx, span := v.startSpan(context.WithoutCancel(ctx), "viber.webhook")
+	defer span.End()
+	span.SetAttribute("event", string(e.Event))
+	span.SetAttribute("user_id", v.redactUser(e.userID()))
+	span.SetAttribute("message_token", e.MessageToken)
+
+	fail := func(status int, err error) {
+		span.RecordError(err)
+		v.fail(w, status, body, err)
+	}
+
+	if v.MaxEventAge > 0 {
+		if d := time.Since(e.Timestamp.Time); d > v.MaxEventAge || d < -v.MaxEventAge {
+			fail(http.StatusForbidden, ErrStaleEvent)
+			return
+		}
+	}
+
+	logger := v.logger().With(slog.String("event", string(e.Event)), v.userAttr(e.userID()), slog.Uint64("message_token", e.MessageToken))
+
+	// event is fully decoded before it is marked, so retry of malformed event is processed
+	var handle func()
 	switch e.Event {
-	case "subscribed":
+	case EventWebhook:
+		handle = func() {
+			// Viber expects status 200 to verify webhook URL
+			webhookVerified(v)
+			if v.WebhookVerified != nil {
+				v.dispatch(ctx, e.Event, func(v *Viber) { v.WebhookVerified(v, e.MessageToken, e.Timestamp.Time) })
+			}
+		}
+
+	case EventSubscribed:
 		if v.Subscribed != nil {
 			var u User
 			if err := json.Unmarshal(e.User, &u); err != nil {
-				Log.Println(err)
+				fail(http.StatusBadRequest, err)
 				return
 			}
-			go v.Subscribed(v, u, e.MessageToken, e.Timestamp.Time)
+			handle = func() {
+				v.dispatch(ctx, e.Event, func(v *Viber) { v.Subscribed(v, u, e.MessageToken, e.Timestamp.Time) })
+			}
 		}
 
-	case "unsubscribed":
+	case EventUnsubscribed:
 		if v.Unsubscribed != nil {
-			go v.Unsubscribed(v, e.UserID, e.MessageToken, e.Timestamp.Time)
+			handle = func() {
+				v.dispatch(ctx, e.Event, func(v *Viber) { v.Unsubscribed(v, e.UserID, e.MessageToken, e.Timestamp.Time) })
+			}
 		}
 
-	case "conversation_started":
+	case EventConversationStarted:
 		if v.ConversationStarted != nil {
 			var u User
 			if err := json.Unmarshal(e.User, &u); err != nil {
-				Log.Println(err)
+				fail(http.StatusBadRequest, err)
 				return
 			}
-			if msg := v.ConversationStarted(v, u, e.Type, e.Context, e.Subscribed, e.MessageToken, e.Timestamp.Time); msg != nil {
+			handle = func() {
+				var msg Message
+				v.callback(e.Event, func() {
+					v := v.callbackBot(ctx)
+					msg = v.ConversationStarted(v, u, e.Type, e.Context, e.Subscribed, e.MessageToken, e.Timestamp.Time)
+				})
+				if msg == nil {
+					return
+				}
+				fm, err := v.Fallback(msg, u.APIVersion)
+				if err != nil {
+					// welcome message user's client can't display is not sent, event is still acknowledged
+					span.RecordError(err)
+					logger.Warn("welcome message not sent", slog.Int("api_version", u.APIVersion), slog.String("error", err.Error()))
+					if v.OnError != nil {
+						v.OnError(v, &WebhookError{StatusCode: http.StatusOK, Body: body, Err: err})
+					}
+					return
+				}
+				msg = fm
 				msg.SetReceiver("")
 				msg.SetFrom("")
+				setMinAPIVersion(msg)
 				b, _ := json.Marshal(msg)
 				w.Write(b)
 			}
 		}
 
-	case "delivered":
+	case EventDelivered:
 		if v.Delivered != nil {
-			go v.Delivered(v, e.UserID, e.MessageToken, e.Timestamp.Time)
+			handle = func() {
+				v.dispatch(ctx, e.Event, func(v *Viber) { v.Delivered(v, e.UserID, e.MessageToken, e.Timestamp.Time) })
+			}
 		}
 
-	case "seen":
+	case EventSeen:
 		if v.Seen != nil {
-			go v.Seen(v, e.UserID, e.MessageToken, e.Timestamp.Time)
+			handle = func() {
+				v.dispatch(ctx, e.Event, func(v *Viber) { v.Seen(v, e.UserID, e.MessageToken, e.Timestamp.Time) })
+			}
 		}
 
-	case "failed":
+	case EventFailed:
 		if v.Failed != nil {
-			go v.Failed(v, e.UserID, e.MessageToken, e.Descr, e.Timestamp.Time)
+			handle = func() {
+				v.dispatch(ctx, e.Event, func(v *Viber) { v.Failed(v, e.UserID, e.MessageToken, e.Descr, e.Timestamp.Time) })
+			}
 		}
 
-	case "message":
-		if v.Message != nil {
+	case EventMessage:
+		var m Message
+		msgType := peakMessageType(e.Message)
+		span.SetAttribute("message_type", msgType)
+		switch msgType {
+		case "text":
+			m = &TextMessage{}
+		case "picture":
+			m = &PictureMessage{}
+		case "video":
+			m = &VideoMessage{}
+		case "url":
+			m = &URLMessage{}
+		case "file":
+			m = &FileMessage{}
+		default:
+			// TODO contact, location
+			handle = func() { v.unknown(ctx, e.Event, body) }
+		}
+
+		if m != nil && v.Message != nil {
 			var u User
 			if err := json.Unmarshal(e.Sender, &u); err != nil {
-				Log.Println(err)
+				fail(http.StatusBadRequest, err)
 				return
 			}
+			if err := json.Unmarshal(e.Message, m); err != nil {
+				fail(http.StatusBadRequest, err)
+				return
+			}
+			handle = func() {
+				v.dispatch(ctx, e.Event, func(v *Viber) { v.Message(v, u, m, e.MessageToken, e.Timestamp.Time) })
+			}
+		}
 
-			msgType := peakMessageType(e.Message)
-			switch msgType {
-			case "text":
-				var m TextMessage
-				if err := json.Unmarshal(e.Message, &m); err != nil {
-					Log.Println(err)
-					return
-				}
-				go v.Message(v, u, &m, e.MessageToken, e.Timestamp.Time)
-
-			case "picture":
-				var m PictureMessage
-				if err := json.Unmarshal(e.Message, &m); err != nil {
-					Log.Println(err)
-					return
-				}
-				go v.Message(v, u, &m, e.MessageToken, e.Timestamp.Time)
+	case EventClientStatus:
+		if v.ClientStatus != nil {
+			cs := ClientStatusEvent{Raw: body}
+			if err := json.Unmarshal(body, &cs); err != nil {
+				fail(http.StatusBadRequest, err)
+				return
+			}
+			handle = func() { v.dispatch(ctx, e.Event, func(v *Viber) { v.ClientStatus(v, cs) }) }
+		}
 
-			case "video":
-				var m VideoMessage
-				if err := json.Unmarshal(e.Message, &m); err != nil {
-					Log.Println(err)
-					return
-				}
-				go v.Message(v, u, &m, e.MessageToken, e.Timestamp.Time)
+	case EventAction:
+		if v.Action != nil {
+			a := ActionEvent{Raw: body}
+			if err := json.Unmarshal(body, &a); err != nil {
+				fail(http.StatusBadRequest, err)
+				return
+			}
+			handle = func() { v.dispatch(ctx, e.Event, func(v *Viber) { v.Action(v, a) }) }
+		}
 
-			case "url":
-				var m URLMessage
-				if err := json.Unmarshal(e.Message, &m); err != nil {
-					Log.Println(err)
-					return
-				}
-				go v.Message(v, u, &m, e.MessageToken, e.Timestamp.Time)
+	default:
+		handle = func() { v.unknown(ctx, e.Event, body) }
+	}
 
-			case "contact":
-				// TODO
-			case "location":
-				// TODO
-			default:
-				return
+	dispatched := false
+	if e.MessageToken != 0 && v.Dedup != nil {
+		key := dedupKey(&e)
+		// check and mark is single step, so retry arriving while callback still runs is dropped
+		if !v.Dedup.Mark(key) {
+			logger.Info("duplicate event")
+			return
+		}
+		// mark is removed if dispatch panics, e.g. on closed WorkerPool, so retry is processed
+		defer func() {
+			if !dispatched {
+				v.Dedup.Forget(key)
 			}
+		}()
+	}
+	logger.Info("event received")
+	if v.Metrics != nil {
+		v.Metrics.Event(e.Event)
+	}
+	v.cacheEventUser(&e)
+
+	if handle != nil {
+		handle()
+	}
+	dispatched = true
+}
+
+// unknown passes event which package can't decode to Unknown callback
+func (v *Viber) unknown(ctx context.Context, event EventType, body []byte) {
+	v.logger().Warn("unknown event", slog.String("event", string(event)))
+	if v.Unknown != nil {
+		v.dispatch(ctx, event, func(v *Viber) { v.Unknown(v, string(event), body) })
+	}
+}
+
+// checkHMAC reports whether messageMAC is a valid HMAC tag for message signed with AppKey or one of VerifyKeys.
+// Matched key is returned.
+func (v *Viber) checkHMAC(message []byte, messageMAC string) (string, bool) {
+	mac, err := hex.DecodeString(messageMAC)
+	if err != nil {
+		return "", false
+	}
+
+	if validMAC(message, mac, v.AppKey) {
+		return v.AppKey, true
+	}
+	for _, key := range v.VerifyKeys {
+		if validMAC(message, mac, key) {
+			return key, true
 		}
 	}
+	return "", false
 }
 
-// checkHMAC reports whether messageMAC is a valid HMAC tag for message.
-func (v *Viber) checkHMAC(message []byte, messageMAC string) bool {
-	hmac := hmac.New(sha256.New, []byte(v.AppKey))
-	hmac.Write(message)
-	return messageMAC == hex.EncodeToString(hmac.Sum(nil))
+// validMAC reports whether mac is HMAC-SHA256 of message signed with key
+func validMAC(message, mac []byte, key string) bool {
+	h := hmac.New(sha256.New, []byte(key))
+	h.Write(message)
+	return hmac.Equal(mac, h.Sum(nil))
 }
 
 // peakMessageType uses regexp to determin message type for unmarshaling
@@ -216,10 +477,13 @@ func peakMessageType(b []byte) string {
 	return strings.ToLower(string(matches[0][1]))
 }
 
-// SetRequestTimeout for sending messages to viber server
+// SetRequestTimeout for sending messages to viber server.
+// Client is copied, so client set with WithHTTPClient or shared with other bots is not modified.
 func (v *Viber) SetRequestTimeout(t time.Duration) {
 	if v.client == nil {
-		v.client = &http.Client{}
+		v.client = newHTTPClient()
 	}
-	v.client.Timeout = t
+	c := *v.client
+	c.Timeout = t
+	v.client = &c
 }
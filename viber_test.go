@@ -0,0 +1,262 @@
+package viber
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// syncDispatcher runs callbacks before webhook request returns
+type syncDispatcher struct{}
+
+func (syncDispatcher) Dispatch(f func()) { f() }
+
+// postWebhook sends webhook request signed with v.AppKey to v
+func postWebhook(v http.Handler, key, body string) *httptest.ResponseRecorder {
+	h := hmac.New(sha256.New, []byte(key))
+	h.Write([]byte(body))
+	r := httptest.NewRequest("POST", "/", strings.NewReader(body))
+	r.Header.Set("X-Viber-Content-Signature", hex.EncodeToString(h.Sum(nil)))
+	w := httptest.NewRecorder()
+	v.ServeHTTP(w, r)
+	return w
+}
+
+func TestDedupAfterDecodeFailure(t *testing.T) {
+	v := New("key", "bot", "")
+	v.Dispatcher = syncDispatcher{}
+	calls := 0
+	v.Message = func(v *Viber, u User, m Message, token uint64, t time.Time) { calls++ }
+
+	bad := `{"event":"message","timestamp":1,"message_token":7,"sender":"broken","message":{"type":"text","text":"hi"}}`
+	if w := postWebhook(v, "key", bad); w.Code != http.StatusBadRequest {
+		t.Fatalf("malformed event status %d, want 400", w.Code)
+	}
+
+	good := `{"event":"message","timestamp":1,"message_token":7,"sender":{"id":"u1"},"message":{"type":"text","text":"hi"}}`
+	if w := postWebhook(v, "key", good); w.Code != http.StatusOK {
+		t.Fatalf("retried event status %d, want 200", w.Code)
+	}
+	if calls != 1 {
+		t.Fatalf("retried event dispatched %d times, want 1", calls)
+	}
+
+	postWebhook(v, "key", good)
+	if calls != 1 {
+		t.Fatalf("duplicate event dispatched, calls %d", calls)
+	}
+}
+
+func TestDedupRetryDuringCallback(t *testing.T) {
+	v := New("key", "bot", "")
+	var calls atomic.Int32
+	v.ConversationStarted = func(v *Viber, u User, conversationType, context string, subscribed bool, token uint64, t time.Time) Message {
+		calls.Add(1)
+		time.Sleep(100 * time.Millisecond)
+		return nil
+	}
+
+	body := `{"event":"conversation_started","timestamp":1,"message_token":7,"user":{"id":"u1"}}`
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			postWebhook(v, "key", body)
+		}()
+		// retry arrives while the first delivery is still in the callback
+		time.Sleep(20 * time.Millisecond)
+	}
+	wg.Wait()
+
+	if n := calls.Load(); n != 1 {
+		t.Fatalf("event retried during callback dispatched %d times, want 1", n)
+	}
+}
+
+func TestDedupForgetOnPanic(t *testing.T) {
+	v := New("key", "bot", "")
+	v.Dispatcher = syncDispatcher{}
+	calls := 0
+	v.Delivered = func(v *Viber, userID string, token uint64, t time.Time) {
+		calls++
+		if calls == 1 {
+			panic("dispatch failed")
+		}
+	}
+
+	body := `{"event":"delivered","timestamp":1,"message_token":7,"user_id":"u1"}`
+	func() {
+		defer func() { recover() }()
+		postWebhook(v, "key", body)
+	}()
+	postWebhook(v, "key", body)
+	if calls != 2 {
+		t.Fatalf("event after failed dispatch called %d times, want 2", calls)
+	}
+}
+
+func TestSignature(t *testing.T) {
+	v := New("key", "bot", "")
+	body := `{"event":"webhook","timestamp":1}`
+	if w := postWebhook(v, "other", body); w.Code != http.StatusUnauthorized {
+		t.Fatalf("wrong signature status %d, want 401", w.Code)
+	}
+	if w := postWebhook(v, "key", body); w.Code != http.StatusOK {
+		t.Fatalf("valid signature status %d, want 200", w.Code)
+	}
+}
+
+func TestMaxEventAge(t *testing.T) {
+	tests := []struct {
+		name   string
+		age    time.Duration
+		status int
+	}{
+		{"recent", time.Minute, http.StatusOK},
+		{"stale", 2 * time.Hour, http.StatusForbidden},
+		{"future", -2 * time.Hour, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		v := New("key", "bot", "")
+		v.MaxEventAge = time.Hour
+		var werr *WebhookError
+		v.OnError = func(v *Viber, err *WebhookError) { werr = err }
+
+		body := fmt.Sprintf(`{"event":"webhook","timestamp":%d}`, time.Now().Add(-tt.age).UnixMilli())
+		w := postWebhook(v, "key", body)
+		if w.Code != tt.status {
+			t.Errorf("%s event status %d, want %d", tt.name, w.Code, tt.status)
+		}
+		if tt.status == http.StatusForbidden && (werr == nil || werr.StatusCode != http.StatusForbidden || werr.Err != ErrStaleEvent) {
+			t.Errorf("%s event reported as %v", tt.name, werr)
+		}
+	}
+}
+
+func TestVerifyKeys(t *testing.T) {
+	tests := []struct {
+		name    string
+		key     string
+		status  int
+		matched string
+	}{
+		{"app key", "key", http.StatusOK, "key"},
+		{"previous key", "old", http.StatusOK, "old"},
+		{"unknown key", "other", http.StatusUnauthorized, ""},
+	}
+	for _, tt := range tests {
+		v := New("key", "bot", "")
+		v.VerifyKeys = []string{"old"}
+		matched := ""
+		v.KeyMatched = func(v *Viber, key string) { matched = key }
+
+		w := postWebhook(v, tt.key, `{"event":"webhook","timestamp":1}`)
+		if w.Code != tt.status || matched != tt.matched {
+			t.Errorf("%s: status %d, matched %q, want %d, %q", tt.name, w.Code, matched, tt.status, tt.matched)
+		}
+	}
+}
+
+func TestWebhookBody(t *testing.T) {
+	large := `{"event":"webhook","timestamp":1,"pad":"` + strings.Repeat("x", 100) + `"}`
+	tests := []struct {
+		name    string
+		body    string
+		chunked bool
+		status  int
+	}{
+		{"valid", `{"event":"webhook","timestamp":1}`, false, http.StatusOK},
+		{"bad json", `{"event":`, false, http.StatusBadRequest},
+		{"too large", large, false, http.StatusRequestEntityTooLarge},
+		{"too large chunked", large, true, http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		v := New("key", "bot", "")
+		v.MaxBodySize = 64
+		var werr *WebhookError
+		v.OnError = func(v *Viber, err *WebhookError) { werr = err }
+
+		var w *httptest.ResponseRecorder
+		if tt.chunked {
+			// unknown length is limited while reading
+			r := httptest.NewRequest("POST", "/", strings.NewReader(tt.body))
+			r.ContentLength = -1
+			w = httptest.NewRecorder()
+			v.ServeHTTP(w, r)
+		} else {
+			w = postWebhook(v, "key", tt.body)
+		}
+
+		if w.Code != tt.status {
+			t.Errorf("%s: status %d, want %d", tt.name, w.Code, tt.status)
+		}
+		if tt.status != http.StatusOK && (werr == nil || werr.StatusCode != tt.status) {
+			t.Errorf("%s: reported as %v", tt.name, werr)
+		}
+		if tt.status == http.StatusRequestEntityTooLarge && werr != nil && werr.Err != ErrBodyTooLarge {
+			t.Errorf("%s: error %v", tt.name, werr.Err)
+		}
+	}
+}
+
+func TestClientStatusAndActionEvents(t *testing.T) {
+	v := New("key", "bot", "")
+	v.Dispatcher = syncDispatcher{}
+	var cs ClientStatusEvent
+	var a ActionEvent
+	v.ClientStatus = func(v *Viber, e ClientStatusEvent) { cs = e }
+	v.Action = func(v *Viber, e ActionEvent) { a = e }
+
+	body := `{"event":"client_status","timestamp":1457764197627,"message_token":3,"user_id":"u1","status":"online","extra":1}`
+	postWebhook(v, "key", body)
+	if cs.Event != EventClientStatus || cs.UserID != "u1" || cs.Status != "online" || cs.MessageToken != 3 ||
+		cs.Timestamp.Unix() != 1457764197 || string(cs.Raw) != body {
+		t.Fatalf("client status event %+v", cs)
+	}
+
+	body = `{"event":"action","timestamp":1,"message_token":4,"user":{"id":"u2","name":"Ann"},"action":{"type":"reaction"}}`
+	postWebhook(v, "key", body)
+	if a.Event != EventAction || a.User == nil || a.User.ID != "u2" || string(a.Action) != `{"type":"reaction"}` || string(a.Raw) != body {
+		t.Fatalf("action event %+v", a)
+	}
+}
+
+func TestUnknownEvents(t *testing.T) {
+	v := New("key", "bot", "")
+	v.Dispatcher = syncDispatcher{}
+	type unknown struct {
+		name string
+		raw  string
+	}
+	var got []unknown
+	v.Unknown = func(v *Viber, eventName string, raw json.RawMessage) {
+		got = append(got, unknown{eventName, string(raw)})
+	}
+	v.Message = func(v *Viber, u User, m Message, token uint64, ts time.Time) {
+		t.Error("unknown message type passed to Message")
+	}
+
+	events := []string{
+		`{"event":"new_event","timestamp":1,"message_token":5}`,
+		`{"event":"message","timestamp":1,"message_token":6,"sender":{"id":"u1"},"message":{"type":"location","location":{"lat":1,"lon":2}}}`,
+	}
+	for _, body := range events {
+		if w := postWebhook(v, "key", body); w.Code != http.StatusOK {
+			t.Fatalf("unknown event status %d", w.Code)
+		}
+	}
+	want := []unknown{{"new_event", events[0]}, {"message", events[1]}}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Fatalf("unknown events %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,49 @@
+package viber
+
+// MaxVideoSize of video message media, 26MB
+const MaxVideoSize = 26 << 20
+
+// NewVideoMessage for viber, size of video in bytes is mandatory, duration in seconds is optional
+func (v *Viber) NewVideoMessage(url string, thumbURL string, size uint, duration uint) *VideoMessage {
+	return &VideoMessage{
+		TextMessage: TextMessage{
+			Sender: v.Sender,
+			Type:   TypeVideoMessage,
+		},
+		Media:     url,
+		Thumbnail: thumbURL,
+		Size:      size,
+		Duration:  duration,
+	}
+}
+
+// SendVideoMessage to receiver, returns message token
+func (v *Viber) SendVideoMessage(receiver string, url string, thumbURL string, size uint, duration uint) (token uint64, err error) {
+	return v.SendMessage(receiver, v.NewVideoMessage(url, thumbURL, size, duration))
+}
+
+// ProbeVideo sets Size of video message m from source, local file path or http(s) URL of the video.
+// Remote video is checked with HEAD request. Empty source probes m.Media.
+// Error is returned if video is larger than MaxVideoSize or isn't MP4.
+// Duration is set if duration is greater than 0.
+func (v *Viber) ProbeVideo(m *VideoMessage, source string, duration uint) error {
+	if source == "" {
+		source = m.Media
+	}
+	info, err := v.probeMedia(source)
+	if err != nil {
+		return &MediaError{Type: TypeVideoMessage, Media: source, Reason: err.Error()}
+	}
+	if err := videoRule.check(source, info); err != nil {
+		return err
+	}
+	if info.size < 0 {
+		return &MediaError{Type: TypeVideoMessage, Media: source, Reason: "unknown size"}
+	}
+
+	m.Size = uint(info.size)
+	if duration > 0 {
+		m.Duration = duration
+	}
+	return nil
+}
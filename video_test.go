@@ -0,0 +1,54 @@
+package viber
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestProbeVideo(t *testing.T) {
+	srv := mediaStub()
+	defer srv.Close()
+	v := New("key", "bot", "")
+
+	dir := t.TempDir()
+	// MP4 is recognized by content, not by extension
+	mp4 := filepath.Join(dir, "clip.dat")
+	os.WriteFile(mp4, []byte("\x00\x00\x00\x18ftypmp42\x00\x00\x00\x00mp42isom"+strings.Repeat("\x00", 100)), 0644)
+	text := filepath.Join(dir, "clip.dat.txt")
+	os.WriteFile(text, []byte("not a video"), 0644)
+
+	tests := []struct {
+		source string
+		size   uint
+		reason string
+	}{
+		{srv.URL + "/clip.mp4", 1000, ""},
+		{mp4, 124, ""},
+		{srv.URL + "/large.mp4", 0, "exceeds"},
+		{srv.URL + "/clip.avi", 0, "unsupported format"},
+		{text, 0, "unsupported format"},
+		{srv.URL + "/stream.mp4", 0, "unknown size"},
+		{srv.URL + "/missing.mp4", 0, "unreachable"},
+		{filepath.Join(dir, "missing.mp4"), 0, "no such file"},
+	}
+	for _, tt := range tests {
+		m := v.NewVideoMessage(tt.source, "", 0, 0)
+		err := v.ProbeVideo(m, "", 7)
+		if tt.reason == "" {
+			if err != nil || m.Size != tt.size || m.Duration != 7 {
+				t.Errorf("%s: size %d, duration %d, err %v", tt.source, m.Size, m.Duration, err)
+			}
+			continue
+		}
+		var me *MediaError
+		if !errors.As(err, &me) || !strings.Contains(me.Reason, tt.reason) {
+			t.Errorf("%s: error %v, want reason %q", tt.source, err, tt.reason)
+		}
+		if m.Size != 0 {
+			t.Errorf("%s: size set to %d on error", tt.source, m.Size)
+		}
+	}
+}
@@ -1,6 +1,14 @@
 package viber
 
-import "encoding/json"
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
 
 //
 //https://chatapi.viber.com/pa/set_webhook
@@ -9,10 +17,36 @@ import "encoding/json"
 //    "event_types": ["delivered", "seen", "failed", "subscribed", "unsubscribed", "conversation_started"]
 // }
 
+// EventType of Viber callback
+type EventType string
+
+// Callback event types
+const (
+	EventMessage             = EventType("message")
+	EventSubscribed          = EventType("subscribed")
+	EventUnsubscribed        = EventType("unsubscribed")
+	EventConversationStarted = EventType("conversation_started")
+	EventDelivered           = EventType("delivered")
+	EventSeen                = EventType("seen")
+	EventFailed              = EventType("failed")
+	EventWebhook             = EventType("webhook")
+	EventClientStatus        = EventType("client_status")
+	EventAction              = EventType("action")
+)
+
+// Event types for SetWebhook
+var (
+	// AllEvents sets all callbacks to webhook
+	AllEvents []EventType
+
+	// MandatoryEvents sets only mandatory callbacks: message, subscribed and unsubscribed
+	MandatoryEvents = []EventType{}
+)
+
 // WebhookReq request
 type WebhookReq struct {
-	URL        string   `json:"url"`
-	EventTypes []string `json:"event_types"`
+	URL        string      `json:"url"`
+	EventTypes []EventType `json:"event_types"`
 }
 
 // {
@@ -23,9 +57,9 @@ type WebhookReq struct {
 
 //WebhookResp response
 type WebhookResp struct {
-	Status        int      `json:"status"`
-	StatusMessage string   `json:"status_message"`
-	EventTypes    []string `json:"event_types,omitempty"`
+	Status        int         `json:"status"`
+	StatusMessage string      `json:"status_message"`
+	EventTypes    []EventType `json:"event_types,omitempty"`
 }
 
 // WebhookVerify response
@@ -35,23 +69,118 @@ type WebhookVerify struct {
 	MessageToken uint64 `json:"message_token"`
 }
 
-// SetWebhook for Viber callbacks
-// if eventTypes is nil, all callbacks will be set to webhook
-// if eventTypes is empty []string mandatory callbacks will be set
-// Mandatory callbacks: "message", "subscribed", "unsubscribed"
-// All possible callbacks: "message", "subscribed",  "unsubscribed", "delivered", "seen", "failed", "conversation_started"
-func (v *Viber) SetWebhook(url string, eventTypes []string) (WebhookResp, error) {
+// SetWebhook for Viber callbacks, webhookURL must be HTTPS URL
+// if eventTypes is nil (AllEvents), all callbacks will be set to webhook
+// if eventTypes is empty (MandatoryEvents) mandatory callbacks will be set
+// Mandatory callbacks: EventMessage, EventSubscribed, EventUnsubscribed
+// Optional callbacks: EventDelivered, EventSeen, EventFailed, EventConversationStarted
+func (v *Viber) SetWebhook(webhookURL string, eventTypes []EventType) (WebhookResp, error) {
+	u, err := url.Parse(webhookURL)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return WebhookResp{}, ErrWebhookURL
+	}
+	return v.setWebhook(WebhookReq{
+		URL:        webhookURL,
+		EventTypes: eventTypes,
+	})
+}
+
+// RemoveWebhook unsets webhook, Viber will stop sending callbacks
+func (v *Viber) RemoveWebhook() error {
+	_, err := v.setWebhook(WebhookReq{})
+	return err
+}
+
+func (v *Viber) setWebhook(req WebhookReq) (WebhookResp, error) {
 	var resp WebhookResp
+	r, err := v.PostData(v.apiURL("set_webhook"), req)
+	if err != nil {
+		return resp, err
+	}
 
-	req := WebhookReq{
-		URL:        url,
-		EventTypes: eventTypes,
+	if err := json.Unmarshal(r, &resp); err != nil {
+		return resp, err
+	}
+
+	if resp.Status != 0 {
+		return resp, Error{Status: resp.Status, StatusMessage: resp.StatusMessage}
 	}
-	r, err := v.PostData("https://chatapi.viber.com/pa/set_webhook", req)
+	return resp, nil
+}
+
+// Webhook setup errors
+var (
+	ErrWebhookURL     = errors.New("viber: webhook URL must be absolute HTTPS URL")
+	ErrWebhookTimeout = errors.New("viber: webhook verification timeout")
+)
+
+// verification waiters by bot AppKey, signaled when webhook event is received.
+// Waiters are keyed by AppKey, so copies of the bot made by WithContext are signaled too.
+var verifyWaiters = struct {
+	sync.Mutex
+	m map[string]map[chan struct{}]bool
+}{m: make(map[string]map[chan struct{}]bool)}
+
+// webhookVerified signals all SetWebhookAndWait calls waiting for bot v
+func webhookVerified(v *Viber) {
+	verifyWaiters.Lock()
+	defer verifyWaiters.Unlock()
+	for ch := range verifyWaiters.m[v.AppKey] {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// SetWebhookAndWait sets webhook and waits until Viber verifies it by sending webhook event.
+// v must already be serving the webhook url.
+func (v *Viber) SetWebhookAndWait(url string, eventTypes []EventType, timeout time.Duration) (WebhookResp, error) {
+	ch := make(chan struct{}, 1)
+	verifyWaiters.Lock()
+	if verifyWaiters.m[v.AppKey] == nil {
+		verifyWaiters.m[v.AppKey] = make(map[chan struct{}]bool)
+	}
+	verifyWaiters.m[v.AppKey][ch] = true
+	verifyWaiters.Unlock()
+
+	defer func() {
+		verifyWaiters.Lock()
+		delete(verifyWaiters.m[v.AppKey], ch)
+		if len(verifyWaiters.m[v.AppKey]) == 0 {
+			delete(verifyWaiters.m, v.AppKey)
+		}
+		verifyWaiters.Unlock()
+	}()
+
+	resp, err := v.SetWebhook(url, eventTypes)
 	if err != nil {
 		return resp, err
 	}
 
-	err = json.Unmarshal(r, &resp)
-	return resp, err
+	select {
+	case <-ch:
+		return resp, nil
+	case <-time.After(timeout):
+		return resp, ErrWebhookTimeout
+	}
+}
+
+// ListenAndSetWebhook starts serving v on TCP network address addr, sets the webhook url
+// and waits for verification. url must reach addr, usually through TLS terminating proxy since Viber requires HTTPS.
+// Server is closed if webhook can't be set.
+func (v *Viber) ListenAndSetWebhook(addr, url string, eventTypes []EventType, timeout time.Duration) (*http.Server, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	srv := &http.Server{Handler: v}
+	go srv.Serve(ln)
+
+	if _, err := v.SetWebhookAndWait(url, eventTypes, timeout); err != nil {
+		srv.Close()
+		return nil, err
+	}
+	return srv, nil
 }
@@ -0,0 +1,124 @@
+package viber
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// verifyingAPI stubs set_webhook which, like Viber, sends signed webhook event to the url before it responds
+func verifyingAPI(t *testing.T, key string, client *http.Client) *httptest.Server {
+	var token atomic.Uint64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req WebhookReq
+		json.NewDecoder(r.Body).Decode(&req)
+		body := fmt.Sprintf(`{"event":"webhook","timestamp":%d,"message_token":%d}`, time.Now().UnixMilli(), token.Add(1))
+		h := hmac.New(sha256.New, []byte(key))
+		h.Write([]byte(body))
+		vr, _ := http.NewRequest("POST", req.URL, bytes.NewReader([]byte(body)))
+		vr.Header.Set("X-Viber-Content-Signature", hex.EncodeToString(h.Sum(nil)))
+		if resp, err := client.Do(vr); err != nil {
+			t.Error(err)
+		} else {
+			resp.Body.Close()
+		}
+		fmt.Fprint(w, `{"status":0,"status_message":"ok"}`)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestSetWebhookAndWait(t *testing.T) {
+	v := New("key", "bot", "")
+	hook := httptest.NewTLSServer(v)
+	defer hook.Close()
+	v.BaseURL = verifyingAPI(t, "key", hook.Client()).URL
+
+	// copy made by WithContext and concurrent calls on the same bot are all verified
+	bots := []*Viber{v, v, v.WithContext(context.Background())}
+	var wg sync.WaitGroup
+	for _, b := range bots {
+		wg.Add(1)
+		go func(b *Viber) {
+			defer wg.Done()
+			if _, err := b.SetWebhookAndWait(hook.URL, nil, 5*time.Second); err != nil {
+				t.Error(err)
+			}
+		}(b)
+	}
+	wg.Wait()
+
+	verifyWaiters.Lock()
+	n := len(verifyWaiters.m)
+	verifyWaiters.Unlock()
+	if n != 0 {
+		t.Fatalf("%d waiters left registered", n)
+	}
+}
+
+func TestSetWebhookAndWaitTimeout(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"status":0,"status_message":"ok"}`)
+	}))
+	defer api.Close()
+
+	v := New("key", "bot", "", WithBaseURL(api.URL))
+	if _, err := v.SetWebhookAndWait("https://example.com/hook", nil, 50*time.Millisecond); err != ErrWebhookTimeout {
+		t.Fatalf("unverified webhook: %v", err)
+	}
+}
+
+func TestSetWebhook(t *testing.T) {
+	var got map[string]interface{}
+	status, calls := 0, 0
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		got = nil
+		json.NewDecoder(r.Body).Decode(&got)
+		fmt.Fprintf(w, `{"status":%d,"status_message":"msg","event_types":["delivered"]}`, status)
+	}))
+	defer api.Close()
+	v := New("key", "bot", "", WithBaseURL(api.URL))
+
+	for _, u := range []string{"http://example.com/hook", "/hook", "https://"} {
+		if _, err := v.SetWebhook(u, nil); err != ErrWebhookURL {
+			t.Errorf("webhook url %q: %v", u, err)
+		}
+	}
+	if calls != 0 {
+		t.Fatal("invalid webhook url sent to API")
+	}
+
+	resp, err := v.SetWebhook("https://example.com/hook", []EventType{EventDelivered})
+	if err != nil || len(resp.EventTypes) != 1 || resp.EventTypes[0] != EventDelivered {
+		t.Fatalf("resp %+v, err %v", resp, err)
+	}
+	if got["url"] != "https://example.com/hook" || fmt.Sprint(got["event_types"]) != "[delivered]" {
+		t.Fatalf("set_webhook payload %v", got)
+	}
+
+	status = 1
+	_, err = v.SetWebhook("https://example.com/hook", nil)
+	if e, ok := err.(Error); !ok || e.Status != 1 || e.StatusMessage != "msg" {
+		t.Fatalf("non-zero status error %#v", err)
+	}
+
+	status = 0
+	if err := v.RemoveWebhook(); err != nil {
+		t.Fatal(err)
+	}
+	// empty url removes the webhook
+	if u, ok := got["url"]; !ok || u != "" {
+		t.Fatalf("remove_webhook payload %v", got)
+	}
+}